@@ -1,7 +1,30 @@
 package g8
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"time"
+)
+
+var (
+	// ErrMissingToken is the reason returned by AuthorizeWithReason if no token was provided
+	ErrMissingToken = errors.New("token is missing")
+
+	// ErrInvalidToken is the reason returned by AuthorizeWithReason if there's no client with the token provided
+	ErrInvalidToken = errors.New("token is invalid")
+
+	// ErrInsufficientPermissions is the reason returned by AuthorizeWithReason if the client does not satisfy the
+	// requirement
+	ErrInsufficientPermissions = errors.New("client does not have the required permissions")
+
+	// ErrTokenExpired is the reason returned by AuthorizeWithReason if the client's token has expired (see
+	// Client.ExpiresAt). Since an expired token is an invalid token, it wraps ErrInvalidToken.
+	ErrTokenExpired = fmt.Errorf("%w: expired", ErrInvalidToken)
+
+	// ErrTokenNotYetValid is the reason returned by AuthorizeWithReason if the client's token is not valid yet (see
+	// Client.NotBefore). Since a token that is not valid yet is an invalid token, it wraps ErrInvalidToken.
+	ErrTokenNotYetValid = fmt.Errorf("%w: not yet valid", ErrInvalidToken)
 )
 
 // AuthorizationService is the service that manages client/token registry and client fallback as well as the service
@@ -10,6 +33,16 @@ type AuthorizationService struct {
 	clients        map[string]*Client
 	clientProvider *ClientProvider
 
+	roles map[string]*Role
+	// rolePermissionSets is the effective permissions of each role, including the permissions of inherited roles.
+	// It is replaced rather than modified whenever a role is registered, so that clients may keep a reference to it.
+	rolePermissionSets map[string]permissionSet
+
+	// now returns the current time, which is used to check whether a client's token has expired
+	now func() time.Time
+	// stopSweeper stops the expired client sweeper, if one was started
+	stopSweeper chan struct{}
+
 	mutex sync.RWMutex
 }
 
@@ -17,6 +50,7 @@ type AuthorizationService struct {
 func NewAuthorizationService() *AuthorizationService {
 	return &AuthorizationService{
 		clients: make(map[string]*Client),
+		now:     time.Now,
 	}
 }
 
@@ -95,6 +129,63 @@ func (authorizationService *AuthorizationService) WithClients(clients []*Client)
 	return authorizationService
 }
 
+// WithRole registers a role, which can then be assigned to clients using Client.WithRole.
+//
+// Roles may inherit other roles, so rather than assigning every permission to each role, you can build a hierarchy:
+//
+//	authorizationService := g8.NewAuthorizationService().
+//		WithRole(g8.NewRole("viewer").WithPermissions([]string{"repo:read", "issues:read"})).
+//		WithRole(g8.NewRole("editor").WithPermission("repo:write").WithInheritedRole("viewer")).
+//		WithRole(g8.NewRole("admin").WithPermission("users:*").WithInheritedRole("editor")).
+//		WithClient(g8.NewClient("token").WithRole("editor"))
+//
+// Inherited roles do not need to be registered before the roles inheriting them, and inherited roles that are never
+// registered are ignored.
+//
+// Panics if the role introduces a cycle in the inheritance graph (e.g. admin inherits editor, which inherits admin),
+// since such a configuration is necessarily a mistake.
+//
+// Registering a role with the same name as a role already registered replaces the latter.
+//
+// The role is copied when it is registered, so modifying it afterward has no effect; to change a role, register it
+// again instead.
+func (authorizationService *AuthorizationService) WithRole(role *Role) *AuthorizationService {
+	role = role.clone()
+	authorizationService.mutex.Lock()
+	defer authorizationService.mutex.Unlock()
+	if authorizationService.roles == nil {
+		authorizationService.roles = make(map[string]*Role)
+	}
+	previousRole := authorizationService.roles[role.Name]
+	authorizationService.roles[role.Name] = role
+	// Any cycle would've been detected when the previous roles were registered, so a new cycle must go through the
+	// role being registered
+	if cycle := findRoleCycle(authorizationService.roles, role.Name); cycle != nil {
+		if previousRole != nil {
+			authorizationService.roles[role.Name] = previousRole
+		} else {
+			delete(authorizationService.roles, role.Name)
+		}
+		panic("g8: role inheritance cycle detected: " + formatRoleCycle(cycle))
+	}
+	rolePermissionSets := make(map[string]permissionSet, len(authorizationService.roles))
+	for roleName, permissions := range resolveRolePermissions(authorizationService.roles) {
+		rolePermissionSets[roleName] = newPermissionSet(permissions)
+	}
+	authorizationService.rolePermissionSets = rolePermissionSets
+	return authorizationService
+}
+
+// WithRoles registers a slice of roles
+//
+// See WithRole for further documentation
+func (authorizationService *AuthorizationService) WithRoles(roles []*Role) *AuthorizationService {
+	for _, role := range roles {
+		authorizationService.WithRole(role)
+	}
+	return authorizationService
+}
+
 // WithClientProvider allows specifying a custom provider to fetch clients by token.
 //
 // For example, you can use it to fallback to making a call in your database when a request is made with a token that
@@ -104,27 +195,164 @@ func (authorizationService *AuthorizationService) WithClientProvider(provider *C
 	return authorizationService
 }
 
+// WithClock sets the function used to get the current time when checking whether a client's token has expired or is
+// not valid yet (see Client.ExpiresAt and Client.NotBefore), which defaults to time.Now.
+//
+// This is mostly useful for testing:
+//
+//	now := time.Now()
+//	authorizationService := g8.NewAuthorizationService().
+//		WithClient(g8.NewClient("token").WithExpiresAt(now.Add(time.Hour))).
+//		WithClock(func() time.Time { return now.Add(2 * time.Hour) })
+func (authorizationService *AuthorizationService) WithClock(now func() time.Time) *AuthorizationService {
+	authorizationService.mutex.Lock()
+	authorizationService.now = now
+	authorizationService.mutex.Unlock()
+	return authorizationService
+}
+
+// WithExpiredClientSweeper starts a goroutine that removes the clients whose token has expired (see Client.ExpiresAt)
+// from the clients registered through WithToken, WithTokens, WithClient or WithClients at every interval.
+//
+// Expired tokens are never authorized whether they've been removed or not, so this is only necessary to free the
+// memory used by temporary clients that would otherwise be kept forever.
+//
+// The goroutine runs until StopExpiredClientSweeper is called. If a sweeper is already running, it is replaced.
+// Panics if interval is not positive.
+func (authorizationService *AuthorizationService) WithExpiredClientSweeper(interval time.Duration) *AuthorizationService {
+	if interval <= 0 {
+		panic("g8: expired client sweeper interval must be positive")
+	}
+	stop := make(chan struct{})
+	authorizationService.mutex.Lock()
+	if authorizationService.stopSweeper != nil {
+		close(authorizationService.stopSweeper)
+	}
+	authorizationService.stopSweeper = stop
+	authorizationService.mutex.Unlock()
+	go authorizationService.sweepExpiredClients(interval, stop)
+	return authorizationService
+}
+
+// StopExpiredClientSweeper stops the goroutine started by WithExpiredClientSweeper, if any
+func (authorizationService *AuthorizationService) StopExpiredClientSweeper() {
+	authorizationService.mutex.Lock()
+	if authorizationService.stopSweeper != nil {
+		close(authorizationService.stopSweeper)
+		authorizationService.stopSweeper = nil
+	}
+	authorizationService.mutex.Unlock()
+}
+
+// sweepExpiredClients removes expired clients at every interval until stop is closed
+func (authorizationService *AuthorizationService) sweepExpiredClients(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			authorizationService.removeExpiredClients()
+		}
+	}
+}
+
+// removeExpiredClients removes the clients whose token has expired and returns how many were removed
+func (authorizationService *AuthorizationService) removeExpiredClients() int {
+	authorizationService.mutex.Lock()
+	defer authorizationService.mutex.Unlock()
+	now, removed := authorizationService.now(), 0
+	for token, client := range authorizationService.clients {
+		if client.isExpired(now) {
+			delete(authorizationService.clients, token)
+			removed++
+		}
+	}
+	return removed
+}
+
 // Authorize checks whether a client with a given token exists and has the permissions required.
 //
 // If permissionsRequired is nil or empty and a client with the given token exists, said client will have access to all
 // handlers that are not protected by a given permission.
 //
+// If the client has roles (see WithRole), the permissions of said roles are taken into account, and the client returned
+// is a copy of the client whose HasPermission method takes them into account as well. Its Permissions are left as is.
+//
 // Returns the client is authorized (or nil if no client was authorized), as well as whether the token is authorized
 func (authorizationService *AuthorizationService) Authorize(token string, permissionsRequired []string) (client *Client, authorized bool) {
+	return authorizationService.AuthorizeRequirement(token, AllPermissions(permissionsRequired...))
+}
+
+// AuthorizeRequirement does the same thing as Authorize, except that the client must satisfy a Requirement rather than
+// have every permission of a slice of permissions, which makes it possible to express any-of (AnyOf) and negated (Not)
+// permission requirements.
+//
+// If requirement is nil and a client with the given token exists, said client will be authorized.
+//
+//	client, authorized := authorizationService.AuthorizeRequirement(token, g8.AnyPermission("admin", "support"))
+func (authorizationService *AuthorizationService) AuthorizeRequirement(token string, requirement Requirement) (client *Client, authorized bool) {
+	if client, err := authorizationService.AuthorizeWithReason(token, requirement); err == nil {
+		return client, true
+	}
+	return nil, false
+}
+
+// AuthorizeWithReason does the same thing as AuthorizeRequirement, except that rather than only reporting whether the
+// client is authorized, it returns the reason why it is not:
+//   - ErrMissingToken if the token is empty
+//   - ErrInvalidToken if there's no client with the given token
+//   - ErrTokenExpired or ErrTokenNotYetValid if the client's token is outside its validity period, both of which
+//     wrap ErrInvalidToken
+//   - ErrInsufficientPermissions if the client does not satisfy the requirement, in which case the client is returned
+//     as well, since its identity is known
+//
+// Returns the client and a nil error if the client is authorized.
+//
+//	client, err := authorizationService.AuthorizeWithReason(token, g8.AllPermissions("admin"))
+//	if errors.Is(err, g8.ErrInsufficientPermissions) {
+//		// The token is valid, but the client is not allowed to do this
+//	}
+func (authorizationService *AuthorizationService) AuthorizeWithReason(token string, requirement Requirement) (client *Client, err error) {
 	if len(token) == 0 {
-		return nil, false
+		return nil, ErrMissingToken
 	}
 	authorizationService.mutex.RLock()
 	client, _ = authorizationService.clients[token]
+	now := authorizationService.now
 	authorizationService.mutex.RUnlock()
 	// If there's no clients with the given token directly stored in the AuthorizationService, fall back to the
 	// client provider, if there's one configured.
 	if client == nil && authorizationService.clientProvider != nil {
 		client = authorizationService.clientProvider.GetClientByToken(token)
 	}
-	if client != nil && client.HasPermissions(permissionsRequired) {
-		// If the client has the required permissions, return true and the client
-		return client, true
+	if client == nil {
+		return nil, ErrInvalidToken
 	}
-	return nil, false
+	if currentTime := now(); client.isExpired(currentTime) {
+		return nil, ErrTokenExpired
+	} else if client.isNotYetValid(currentTime) {
+		return nil, ErrTokenNotYetValid
+	}
+	if len(client.Roles) > 0 {
+		client = authorizationService.resolveRoles(client)
+	}
+	if requirement != nil && !requirement.IsSatisfiedBy(client) {
+		return client, ErrInsufficientPermissions
+	}
+	return client, nil
+}
+
+// resolveRoles returns a copy of a client that looks up the effective permissions of the client's roles when checking
+// whether the client has a permission, so that said permissions are taken into account when checking whether the
+// client satisfies a Requirement.
+func (authorizationService *AuthorizationService) resolveRoles(client *Client) *Client {
+	authorizationService.mutex.RLock()
+	rolePermissionSets := authorizationService.rolePermissionSets
+	authorizationService.mutex.RUnlock()
+	resolvedClient := *client
+	resolvedClient.rolePermissionSets = rolePermissionSets
+	resolvedClient.permissionSetShared = true
+	return &resolvedClient
 }
@@ -1,6 +1,10 @@
 package g8
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestAuthorizationService_Authorize(t *testing.T) {
 	authorizationService := NewAuthorizationService().WithToken("token")
@@ -106,3 +110,232 @@ func TestAuthorizationService_WithClients(t *testing.T) {
 		t.Error("should've returned false")
 	}
 }
+
+func TestAuthorizationService_AuthorizeRequirement(t *testing.T) {
+	authorizationService := NewAuthorizationService().WithClient(NewClient("token").WithPermissions([]string{"support", "intern"}))
+	if _, authorized := authorizationService.AuthorizeRequirement("token", nil); !authorized {
+		t.Error("should've returned true")
+	}
+	if _, authorized := authorizationService.AuthorizeRequirement("token", AnyPermission("admin", "support")); !authorized {
+		t.Error("should've returned true")
+	}
+	if _, authorized := authorizationService.AuthorizeRequirement("token", AnyPermission("admin", "owner")); authorized {
+		t.Error("should've returned false")
+	}
+	if _, authorized := authorizationService.AuthorizeRequirement("token", AllOf(Permission("support"), Not(Permission("intern")))); authorized {
+		t.Error("should've returned false")
+	}
+	if _, authorized := authorizationService.AuthorizeRequirement("bad-token", nil); authorized {
+		t.Error("should've returned false")
+	}
+}
+
+func TestAuthorizationService_WithRole(t *testing.T) {
+	authorizationService := NewAuthorizationService().
+		WithClients([]*Client{NewClient("viewer-token").WithRole("viewer"), NewClient("admin-token").WithRole("admin").WithPermission("billing")}).
+		// Roles can be registered in any order, and after the clients that use them
+		WithRole(NewRole("admin").WithPermission("users:*").WithInheritedRole("editor")).
+		WithRoles([]*Role{NewRole("viewer").WithPermission("repo:read"), NewRole("editor").WithPermission("repo:write").WithInheritedRole("viewer")})
+	if _, authorized := authorizationService.Authorize("viewer-token", []string{"repo:read"}); !authorized {
+		t.Error("should've returned true")
+	}
+	if _, authorized := authorizationService.Authorize("viewer-token", []string{"repo:write"}); authorized {
+		t.Error("should've returned false")
+	}
+	client, authorized := authorizationService.Authorize("admin-token", []string{"repo:read", "repo:write", "users:delete", "billing"})
+	if !authorized {
+		t.Fatal("should've returned true")
+	}
+	if !client.HasPermission("repo:read") {
+		t.Error("expected the client returned to have the permissions of its roles")
+	}
+	if len(client.Permissions) != 1 || !client.isPermissionSetInSync() {
+		t.Errorf("expected the client returned to keep its own indexed permissions, got permissions %v", client.Permissions)
+	}
+	original := authorizationService.clients["admin-token"]
+	if len(original.Permissions) != 1 || original.HasPermission("repo:read") {
+		t.Errorf("expected the registered client to not have been modified, got permissions %v", original.Permissions)
+	}
+	// Adding a permission to the client returned must not add it to the registered client, with which it shares its
+	// index
+	client.WithPermission("deploy")
+	if !client.HasPermission("deploy") || !client.HasPermission("repo:read") {
+		t.Error("expected the client returned to have both the permission added and the permissions of its roles")
+	}
+	if original.HasPermission("deploy") {
+		t.Error("expected the permission added to the client returned to not have been added to the registered client")
+	}
+	if _, authorized := authorizationService.Authorize("admin-token", []string{"deploy"}); authorized {
+		t.Error("should've returned false")
+	}
+}
+
+func TestAuthorizationService_WithRoleWithCycle(t *testing.T) {
+	authorizationService := NewAuthorizationService().
+		WithRole(NewRole("viewer").WithInheritedRole("admin")).
+		WithRole(NewRole("editor").WithInheritedRole("viewer"))
+	defer func() {
+		if r := recover(); r != "g8: role inheritance cycle detected: admin -> editor -> viewer -> admin" {
+			t.Errorf("expected panic because of cycle, got %v", r)
+		}
+		if _, exists := authorizationService.roles["admin"]; exists {
+			t.Error("expected the role introducing the cycle to not have been registered")
+		}
+	}()
+	authorizationService.WithRole(NewRole("admin").WithInheritedRole("editor"))
+}
+
+func TestAuthorizationService_WithRoleModifiedAfterRegistration(t *testing.T) {
+	viewer := NewRole("viewer").WithPermission("read")
+	editor := NewRole("editor").WithPermission("write").WithInheritedRole("viewer")
+	authorizationService := NewAuthorizationService().
+		WithRoles([]*Role{viewer, editor}).
+		WithClient(NewClient("token").WithRole("viewer"))
+	// Modifying a role after it has been registered must have no effect, including when it would introduce a cycle
+	viewer.WithPermission("delete").WithInheritedRole("editor")
+	if _, authorized := authorizationService.Authorize("token", []string{"delete"}); authorized {
+		t.Error("expected the permission added after registration to have been ignored")
+	}
+	if _, authorized := authorizationService.Authorize("token", []string{"write"}); authorized {
+		t.Error("expected the role inherited after registration to have been ignored")
+	}
+	authorizationService.WithRole(NewRole("admin").WithInheritedRole("editor"))
+	if _, authorized := authorizationService.Authorize("token", []string{"read"}); !authorized {
+		t.Error("should've returned true")
+	}
+}
+
+func TestAuthorizationService_AuthorizeWithReason(t *testing.T) {
+	authorizationService := NewAuthorizationService().WithClient(NewClient("token").WithPermission("read"))
+	scenarios := []struct {
+		name           string
+		token          string
+		requirement    Requirement
+		expectedClient bool
+		expectedErr    error
+	}{
+		{name: "authorized", token: "token", requirement: AllPermissions("read"), expectedClient: true, expectedErr: nil},
+		{name: "authorized-without-requirement", token: "token", requirement: nil, expectedClient: true, expectedErr: nil},
+		{name: "missing-token", token: "", requirement: nil, expectedClient: false, expectedErr: ErrMissingToken},
+		{name: "invalid-token", token: "bad-token", requirement: nil, expectedClient: false, expectedErr: ErrInvalidToken},
+		{name: "insufficient-permissions", token: "token", requirement: AllPermissions("write"), expectedClient: true, expectedErr: ErrInsufficientPermissions},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			client, err := authorizationService.AuthorizeWithReason(scenario.token, scenario.requirement)
+			if !errors.Is(err, scenario.expectedErr) {
+				t.Errorf("expected error to be %v, got %v", scenario.expectedErr, err)
+			}
+			if (client != nil) != scenario.expectedClient {
+				t.Errorf("expected client to be returned: %v, got %v", scenario.expectedClient, client)
+			}
+		})
+	}
+}
+
+func TestAuthorizationService_AuthorizeWithExpiresAtAndNotBefore(t *testing.T) {
+	start := time.Now()
+	now := start
+	authorizationService := NewAuthorizationService().
+		WithClient(NewClient("temporary-token").WithExpiresAt(start.Add(time.Hour))).
+		WithClient(NewClient("future-token").WithNotBefore(start.Add(time.Hour))).
+		WithClientProvider(NewClientProvider(func(token string) *Client {
+			if token == "provided-token" {
+				return NewClient(token).WithExpiresAt(start.Add(time.Hour))
+			}
+			return nil
+		})).
+		WithClock(func() time.Time { return now })
+	scenarios := []struct {
+		name        string
+		token       string
+		now         time.Time
+		expectedErr error
+	}{
+		{name: "temporary-token-before-expiry", token: "temporary-token", now: start, expectedErr: nil},
+		{name: "temporary-token-after-expiry", token: "temporary-token", now: start.Add(time.Hour), expectedErr: ErrTokenExpired},
+		{name: "future-token-before-not-before", token: "future-token", now: start, expectedErr: ErrTokenNotYetValid},
+		{name: "future-token-after-not-before", token: "future-token", now: start.Add(time.Hour), expectedErr: nil},
+		{name: "provided-token-before-expiry", token: "provided-token", now: start, expectedErr: nil},
+		{name: "provided-token-after-expiry", token: "provided-token", now: start.Add(2 * time.Hour), expectedErr: ErrTokenExpired},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			now = scenario.now
+			client, err := authorizationService.AuthorizeWithReason(scenario.token, nil)
+			if !errors.Is(err, scenario.expectedErr) {
+				t.Errorf("expected error to be %v, got %v", scenario.expectedErr, err)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("expected error %v to wrap %v", err, ErrInvalidToken)
+				}
+				if client != nil {
+					t.Errorf("expected no client to be returned, got %v", client)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizationService_removeExpiredClients(t *testing.T) {
+	now := time.Now()
+	authorizationService := NewAuthorizationService().
+		WithToken("token").
+		WithClient(NewClient("expired-token").WithExpiresAt(now.Add(-time.Minute))).
+		WithClient(NewClient("temporary-token").WithExpiresAt(now.Add(time.Minute))).
+		WithClient(NewClient("future-token").WithNotBefore(now.Add(time.Minute))).
+		WithClock(func() time.Time { return now })
+	if removed := authorizationService.removeExpiredClients(); removed != 1 {
+		t.Errorf("expected %d client to be removed, got %d", 1, removed)
+	}
+	if _, exists := authorizationService.clients["expired-token"]; exists {
+		t.Error("expected expired-token to have been removed")
+	}
+	if len(authorizationService.clients) != 3 {
+		t.Errorf("expected %d clients to be left, got %d", 3, len(authorizationService.clients))
+	}
+}
+
+func TestAuthorizationService_WithExpiredClientSweeper(t *testing.T) {
+	authorizationService := NewAuthorizationService().
+		WithToken("token").
+		WithClient(NewClient("temporary-token").WithExpiresAt(time.Now().Add(50 * time.Millisecond))).
+		WithExpiredClientSweeper(10 * time.Millisecond)
+	defer authorizationService.StopExpiredClientSweeper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		authorizationService.mutex.RLock()
+		_, exists := authorizationService.clients["temporary-token"]
+		authorizationService.mutex.RUnlock()
+		if !exists {
+			break
+		}
+	}
+	authorizationService.mutex.RLock()
+	defer authorizationService.mutex.RUnlock()
+	if _, exists := authorizationService.clients["temporary-token"]; exists {
+		t.Error("expected temporary-token to have been removed by the sweeper")
+	}
+	if _, exists := authorizationService.clients["token"]; !exists {
+		t.Error("expected token not to have been removed by the sweeper")
+	}
+}
+
+func TestAuthorizationService_StopExpiredClientSweeper(t *testing.T) {
+	authorizationService := NewAuthorizationService().WithExpiredClientSweeper(time.Hour).WithExpiredClientSweeper(time.Hour)
+	authorizationService.StopExpiredClientSweeper()
+	if authorizationService.stopSweeper != nil {
+		t.Error("expected sweeper to have been stopped")
+	}
+	// Stopping a sweeper that isn't running must not panic
+	authorizationService.StopExpiredClientSweeper()
+}
+
+func TestAuthorizationService_WithExpiredClientSweeperWithInvalidInterval(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewAuthorizationService().WithExpiredClientSweeper(0)
+}
@@ -0,0 +1,85 @@
+package g8
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	// WWWAuthenticateHeader is the header in which Gate writes the challenge (RFC 6750) of a request that was rejected
+	// because its token is missing or invalid, or because its client does not have the required permissions
+	WWWAuthenticateHeader = "WWW-Authenticate"
+)
+
+// newBearerChallenge creates the value of the WWW-Authenticate header for a request that was not authorized for the
+// given reason, as described by RFC 6750.
+//
+// If the token is missing, the challenge does not include an error code, since the client may simply not know that
+// authentication is required. If the token is invalid, the error code is invalid_token, and if the client does not
+// have the required permissions, the error code is insufficient_scope, along with the scope required by the
+// requirement, if any.
+func newBearerChallenge(realm string, reason error, requirement Requirement) string {
+	var parameters []string
+	if len(realm) > 0 {
+		parameters = append(parameters, "realm="+quoteChallengeParameter(realm))
+	}
+	if errors.Is(reason, ErrInsufficientPermissions) {
+		parameters = append(parameters, `error="insufficient_scope"`)
+		if scopes := requirementScopes(requirement); len(scopes) > 0 {
+			parameters = append(parameters, "scope="+quoteChallengeParameter(strings.Join(scopes, " ")))
+		}
+	} else if !errors.Is(reason, ErrMissingToken) {
+		parameters = append(parameters, `error="invalid_token"`)
+	}
+	if len(parameters) == 0 {
+		return "Bearer"
+	}
+	return "Bearer " + strings.Join(parameters, ", ")
+}
+
+// quoteChallengeParameter quotes the value of a challenge parameter, escaping backslashes and double quotes
+func quoteChallengeParameter(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// requirementScopes returns the permissions that a Requirement requires clients to have, which are the permissions
+// that are not negated by Not.
+//
+// Permissions that are not valid scope tokens (RFC 6749), such as permissions that contain whitespace, are omitted,
+// as are the permissions of requirements not provided by g8.
+func requirementScopes(requirement Requirement) []string {
+	var scopes []string
+	var collect func(requirement Requirement)
+	collect = func(requirement Requirement) {
+		switch r := requirement.(type) {
+		case permissionRequirement:
+			if isValidScope(string(r)) && !slices.Contains(scopes, string(r)) {
+				scopes = append(scopes, string(r))
+			}
+		case allOfRequirement:
+			for _, subRequirement := range r {
+				collect(subRequirement)
+			}
+		case anyOfRequirement:
+			for _, subRequirement := range r {
+				collect(subRequirement)
+			}
+		}
+	}
+	collect(requirement)
+	return scopes
+}
+
+// isValidScope checks whether a permission is a valid scope token as defined by RFC 6749, section 3.3
+func isValidScope(permission string) bool {
+	if len(permission) == 0 {
+		return false
+	}
+	for i := 0; i < len(permission); i++ {
+		if character := permission[i]; character < 0x21 || character > 0x7e || character == '"' || character == '\\' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package g8
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNewBearerChallenge(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		realm       string
+		reason      error
+		requirement Requirement
+		expected    string
+	}{
+		{name: "missing-token", reason: ErrMissingToken, expected: `Bearer`},
+		{name: "missing-token-with-realm", realm: "example", reason: ErrMissingToken, expected: `Bearer realm="example"`},
+		{name: "invalid-token", reason: ErrInvalidToken, expected: `Bearer error="invalid_token"`},
+		{name: "invalid-token-with-realm", realm: "example", reason: ErrInvalidToken, expected: `Bearer realm="example", error="invalid_token"`},
+		{name: "unknown-reason", reason: errors.New("unknown"), expected: `Bearer error="invalid_token"`},
+		{name: "insufficient-permissions", reason: ErrInsufficientPermissions, requirement: AllPermissions("repo:read", "repo:write"), expected: `Bearer error="insufficient_scope", scope="repo:read repo:write"`},
+		{name: "insufficient-permissions-with-realm", realm: "example", reason: ErrInsufficientPermissions, requirement: AllPermissions("admin"), expected: `Bearer realm="example", error="insufficient_scope", scope="admin"`},
+		{name: "insufficient-permissions-without-scope", reason: ErrInsufficientPermissions, requirement: nil, expected: `Bearer error="insufficient_scope"`},
+		{name: "realm-with-quotes", realm: `my "special" \realm`, reason: ErrMissingToken, expected: `Bearer realm="my \"special\" \\realm"`},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if challenge := newBearerChallenge(scenario.realm, scenario.reason, scenario.requirement); challenge != scenario.expected {
+				t.Errorf("expected %s, got %s", scenario.expected, challenge)
+			}
+		})
+	}
+}
+
+func TestRequirementScopes(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		requirement Requirement
+		expected    []string
+	}{
+		{name: "nil", requirement: nil, expected: nil},
+		{name: "all-permissions", requirement: AllPermissions("a", "b"), expected: []string{"a", "b"}},
+		{name: "any-permission", requirement: AnyPermission("a", "b"), expected: []string{"a", "b"}},
+		{name: "nested-with-not", requirement: AllOf(AnyOf(Permission("a"), Permission("b")), Not(Permission("c")), Permission("a")), expected: []string{"a", "b"}},
+		{name: "invalid-scopes", requirement: AllPermissions("has space", `has"quote`, "", "valid"), expected: []string{"valid"}},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if scopes := requirementScopes(scenario.requirement); !slices.Equal(scopes, scenario.expected) {
+				t.Errorf("expected %v, got %v", scenario.expected, scopes)
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package g8
 
+import (
+	"slices"
+	"time"
+)
+
 // Client is a struct containing both a Token and a slice of extra Permissions that said token has.
 type Client struct {
 	// Token is the value used to authenticate with the API.
@@ -9,10 +14,47 @@ type Client struct {
 	//
 	// If you only wish to use Gate.Protect and Gate.ProtectFunc, you do not have to worry about this,
 	// since they're only used by Gate.ProtectWithPermissions and Gate.ProtectFuncWithPermissions
+	//
+	// Permissions added through WithPermission, WithPermissions or SetPermissions are indexed so that HasPermission
+	// does not have to go through every permission. If you assign this field directly instead, HasPermission will
+	// still work, but it will fall back to going through every permission until the index is rebuilt by one of the
+	// aforementioned functions or RevokePermission.
+	//
+	// Note that modifying the elements of this slice in place is not supported, as HasPermission would keep using an
+	// outdated index. This includes reassigning this field to a slice that shares the same underlying array and has the
+	// same length (e.g. using append on a subslice of it). To revoke permissions, use RevokePermission or
+	// SetPermissions instead.
 	Permissions []string
 
+	// Roles is a slice of names of roles whose permissions the client has, in addition to Permissions.
+	//
+	// Roles must be registered on the AuthorizationService using AuthorizationService.WithRole.
+	Roles []string
+
+	// rolePermissionSets is the effective permissions of every role registered on the AuthorizationService that
+	// authorized the client, which HasPermission looks up for each of the client's Roles.
+	rolePermissionSets map[string]permissionSet
+
 	// Data is a field that can be used to store any data you want to associate with the client.
 	Data any
+
+	// ExpiresAt is the time at which the client's token expires, after which AuthorizationService no longer
+	// authorizes it. A zero value means that the token never expires.
+	ExpiresAt time.Time
+
+	// NotBefore is the time before which AuthorizationService does not authorize the client's token yet.
+	// A zero value means that the token is valid immediately.
+	NotBefore time.Time
+
+	// permissionSet is an index of Permissions maintained by WithPermission and WithPermissions
+	permissionSet permissionSet
+	// permissionSetShared is whether permissionSet is shared with the client the client was copied from, in which case
+	// it must be rebuilt rather than modified
+	permissionSetShared bool
+	// indexedPermissions and indexedPermissionsArray are the length and the first element of Permissions when
+	// permissionSet was last updated, which are used to detect whether Permissions has been assigned directly since
+	indexedPermissions      int
+	indexedPermissionsArray *string
 }
 
 // NewClient creates a Client with a given token
@@ -42,13 +84,77 @@ func NewClientWithPermissionsAndData(token string, permissions []string, data an
 
 // WithPermissions adds a slice of permissions to a client
 func (client *Client) WithPermissions(permissions []string) *Client {
-	client.Permissions = append(client.Permissions, permissions...)
+	client.addPermissions(permissions...)
 	return client
 }
 
 // WithPermission adds a permission to a client
 func (client *Client) WithPermission(permission string) *Client {
-	client.Permissions = append(client.Permissions, permission)
+	client.addPermissions(permission)
+	return client
+}
+
+// SetPermissions replaces the permissions of a client
+func (client *Client) SetPermissions(permissions []string) *Client {
+	client.Permissions = nil
+	client.permissionSet = nil
+	client.permissionSetShared = false
+	client.addPermissions(permissions...)
+	return client
+}
+
+// RevokePermission removes a permission from a client.
+//
+// Only the permission itself is removed, so if the client also has a permission that implies it (e.g. "admin" for
+// "admin:users"), the client still has it.
+func (client *Client) RevokePermission(permission string) *Client {
+	return client.SetPermissions(slices.DeleteFunc(slices.Clone(client.Permissions), func(p string) bool {
+		return p == permission
+	}))
+}
+
+// addPermissions appends permissions to Permissions and adds them to permissionSet
+func (client *Client) addPermissions(permissions ...string) {
+	if client.permissionSetShared || !client.isPermissionSetInSync() {
+		// Permissions was assigned directly since it was last indexed, or the index belongs to another client, so the
+		// index must be rebuilt from scratch
+		client.permissionSet = newPermissionSet(client.Permissions)
+		if client.permissionSetShared {
+			// Make sure that the permissions appended below do not end up in the underlying array shared with the
+			// other client
+			client.Permissions = slices.Clip(client.Permissions)
+			client.permissionSetShared = false
+		}
+	}
+	client.Permissions = append(client.Permissions, permissions...)
+	client.permissionSet.add(permissions...)
+	client.indexedPermissions = len(client.Permissions)
+	if len(client.Permissions) > 0 {
+		client.indexedPermissionsArray = &client.Permissions[0]
+	}
+}
+
+// isPermissionSetInSync checks whether permissionSet reflects Permissions, which is the case unless Permissions was
+// assigned directly since it was last indexed.
+//
+// Only the length and the underlying array of Permissions are compared so that the check does not depend on the
+// number of permissions, which is why modifying the elements of Permissions in place is not supported.
+func (client *Client) isPermissionSetInSync() bool {
+	if client.permissionSet == nil || client.indexedPermissions != len(client.Permissions) {
+		return false
+	}
+	return len(client.Permissions) == 0 || &client.Permissions[0] == client.indexedPermissionsArray
+}
+
+// WithRoles assigns a slice of roles to a client
+func (client *Client) WithRoles(roleNames []string) *Client {
+	client.Roles = append(client.Roles, roleNames...)
+	return client
+}
+
+// WithRole assigns a role to a client
+func (client *Client) WithRole(roleName string) *Client {
+	client.Roles = append(client.Roles, roleName)
 	return client
 }
 
@@ -58,10 +164,77 @@ func (client *Client) WithData(data any) *Client {
 	return client
 }
 
-// HasPermission checks whether a client has a given permission
+// WithExpiresAt sets the time at which the client's token expires
+func (client *Client) WithExpiresAt(expiresAt time.Time) *Client {
+	client.ExpiresAt = expiresAt
+	return client
+}
+
+// WithNotBefore sets the time before which the client's token is not valid yet
+func (client *Client) WithNotBefore(notBefore time.Time) *Client {
+	client.NotBefore = notBefore
+	return client
+}
+
+// isExpired checks whether the client's token has expired at a given time
+func (client *Client) isExpired(now time.Time) bool {
+	return !client.ExpiresAt.IsZero() && !now.Before(client.ExpiresAt)
+}
+
+// isNotYetValid checks whether the client's token is not valid yet at a given time
+func (client *Client) isNotYetValid(now time.Time) bool {
+	return !client.NotBefore.IsZero() && now.Before(client.NotBefore)
+}
+
+// HasPermission checks whether a client has a given permission.
+//
+// Permissions are hierarchical, and wildcards are supported, so a client has a permission if it has been granted said
+// permission, a permission above it (e.g. "admin" for "admin:users"), a wildcard covering it (e.g. "repo:*" for
+// "repo:read") or the PermissionWildcard itself.
+//
+// For clients returned by an AuthorizationService, the permissions of the client's Roles are taken into account too.
 func (client *Client) HasPermission(permissionRequired string) bool {
+	return client.hasPermission(permissionRequired, client.isPermissionSetInSync())
+}
+
+// hasPermission checks whether a client has a given permission, using permissionSet if permissionSetInSync is true
+func (client *Client) hasPermission(permissionRequired string, permissionSetInSync bool) bool {
+	if client.hasOwnPermission(permissionRequired, permissionSetInSync) {
+		return true
+	}
+	for _, roleName := range client.Roles {
+		if client.rolePermissionSets[roleName].implies(permissionRequired) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOwnPermission checks whether the client's Permissions, as opposed to its Roles, grant a given permission
+func (client *Client) hasOwnPermission(permissionRequired string, permissionSetInSync bool) bool {
+	if permissionSetInSync {
+		return client.permissionSet.implies(permissionRequired)
+	}
 	for _, permission := range client.Permissions {
-		if permissionRequired == permission {
+		if permissionImplies(permission, permissionRequired) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExplicitPermission checks whether a client has been granted a given permission itself, whether directly or
+// through one of its Roles, as opposed to a permission that implies it
+func (client *Client) hasExplicitPermission(permission string) bool {
+	if client.isPermissionSetInSync() {
+		if _, exists := client.permissionSet[permission]; exists {
+			return true
+		}
+	} else if slices.Contains(client.Permissions, permission) {
+		return true
+	}
+	for _, roleName := range client.Roles {
+		if _, exists := client.rolePermissionSets[roleName][permission]; exists {
 			return true
 		}
 	}
@@ -70,8 +243,10 @@ func (client *Client) HasPermission(permissionRequired string) bool {
 
 // HasPermissions checks whether a client has the all permissions passed
 func (client *Client) HasPermissions(permissionsRequired []string) bool {
+	// Whether permissionSet can be used only needs to be checked once, rather than once per permission required
+	permissionSetInSync := client.isPermissionSetInSync()
 	for _, permissionRequired := range permissionsRequired {
-		if !client.HasPermission(permissionRequired) {
+		if !client.hasPermission(permissionRequired, permissionSetInSync) {
 			return false
 		}
 	}
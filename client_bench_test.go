@@ -0,0 +1,107 @@
+package g8
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newBenchmarkPermissions creates a slice of distinct permissions such as the ones a service account may have
+func newBenchmarkPermissions(n int) []string {
+	permissions := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		permissions = append(permissions, "service:resource-"+strconv.Itoa(i)+":read")
+	}
+	return permissions
+}
+
+// benchmarkPermissionCounts are the numbers of permissions of the clients used by the benchmarks, which show that
+// looking up an indexed permission takes the same time regardless of the number of permissions
+var benchmarkPermissionCounts = []int{10, 100, 1000}
+
+func BenchmarkClient_HasPermission(b *testing.B) {
+	for _, numberOfPermissions := range benchmarkPermissionCounts {
+		permissions := newBenchmarkPermissions(numberOfPermissions)
+		for _, scenario := range []struct {
+			name   string
+			client *Client
+		}{
+			// Clients created through WithPermissions have their permissions indexed
+			{name: "indexed", client: NewClient("token").WithPermissions(permissions)},
+			// Clients whose Permissions are assigned directly fall back to going through every permission
+			{name: "not-indexed", client: &Client{Token: "token", Permissions: permissions}},
+		} {
+			b.Run(fmt.Sprintf("%s-%d", scenario.name, numberOfPermissions), func(b *testing.B) {
+				permissionRequired := permissions[len(permissions)-1]
+				for n := 0; n < b.N; n++ {
+					if !scenario.client.HasPermission(permissionRequired) {
+						b.Fatalf("expected client to have permission %s", permissionRequired)
+					}
+				}
+				b.ReportAllocs()
+			})
+		}
+	}
+}
+
+func BenchmarkClient_HasPermissions(b *testing.B) {
+	for _, numberOfPermissions := range benchmarkPermissionCounts {
+		permissions := newBenchmarkPermissions(numberOfPermissions)
+		permissionsRequired := permissions[len(permissions)-10:]
+		for _, scenario := range []struct {
+			name   string
+			client *Client
+		}{
+			{name: "indexed", client: NewClient("token").WithPermissions(permissions)},
+			{name: "not-indexed", client: &Client{Token: "token", Permissions: permissions}},
+		} {
+			b.Run(fmt.Sprintf("%s-%d", scenario.name, numberOfPermissions), func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					if !scenario.client.HasPermissions(permissionsRequired) {
+						b.Fatal("expected client to have every permission required")
+					}
+				}
+				b.ReportAllocs()
+			})
+		}
+	}
+}
+
+func BenchmarkGate_ProtectWithPermissionsAndValidTokenWithManyPermissions(b *testing.B) {
+	for _, numberOfPermissions := range benchmarkPermissionCounts {
+		permissions := newBenchmarkPermissions(numberOfPermissions)
+		gate := New().WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermissions(permissions)))
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "token"))
+
+		router := http.NewServeMux()
+		router.Handle("/handle", gate.ProtectWithPermissions(handler, permissions[len(permissions)-10:]))
+
+		b.Run(strconv.Itoa(numberOfPermissions), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				responseRecorder := httptest.NewRecorder()
+				router.ServeHTTP(responseRecorder, request)
+				if responseRecorder.Code != http.StatusOK {
+					b.Fatalf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
+				}
+			}
+			b.ReportAllocs()
+		})
+	}
+}
+
+func BenchmarkAuthorizationService_AuthorizeWithRoleWithManyPermissions(b *testing.B) {
+	permissions := newBenchmarkPermissions(500)
+	authorizationService := NewAuthorizationService().
+		WithRole(NewRole("service").WithPermissions(permissions)).
+		WithClient(NewClient("token").WithRole("service"))
+	permissionsRequired := permissions[len(permissions)-10:]
+	for n := 0; n < b.N; n++ {
+		if _, authorized := authorizationService.Authorize("token", permissionsRequired); !authorized {
+			b.Fatal("expected client to be authorized")
+		}
+	}
+	b.ReportAllocs()
+}
@@ -1,6 +1,10 @@
 package g8
 
-import "testing"
+import (
+	"slices"
+	"testing"
+	"time"
+)
 
 func TestClient_HasPermission(t *testing.T) {
 	client := NewClientWithPermissions("token", []string{"a", "b"})
@@ -74,3 +78,149 @@ func TestNewClientWithPermissionsAndData(t *testing.T) {
 		t.Errorf("client has permissions %s, therefore HasPermission(c) should've been false", client.Permissions)
 	}
 }
+
+func TestClient_HasPermissionWithWildcardsAndHierarchy(t *testing.T) {
+	client := NewClientWithPermissions("token", []string{"repo:*", "admin", "billing:invoices:read"})
+	for _, permission := range []string{"repo:read", "repo:write", "admin", "admin:users", "admin:users:delete", "billing:invoices:read"} {
+		if !client.HasPermission(permission) {
+			t.Errorf("client has permissions %s, therefore HasPermission(%s) should've been true", client.Permissions, permission)
+		}
+	}
+	for _, permission := range []string{"repo", "billing", "billing:invoices", "billing:invoices:write", "administrator"} {
+		if client.HasPermission(permission) {
+			t.Errorf("client has permissions %s, therefore HasPermission(%s) should've been false", client.Permissions, permission)
+		}
+	}
+	if !NewClientWithPermissions("token", []string{"*"}).HasPermissions([]string{"repo:read", "admin", "billing"}) {
+		t.Error("client has permission *, therefore HasPermissions should've been true")
+	}
+}
+
+func TestClient_WithRoles(t *testing.T) {
+	client := NewClient("token").WithRole("viewer").WithRoles([]string{"editor", "billing"})
+	if len(client.Roles) != 3 || client.Roles[0] != "viewer" || client.Roles[1] != "editor" || client.Roles[2] != "billing" {
+		t.Errorf("expected client roles to be [viewer editor billing], got %v", client.Roles)
+	}
+}
+
+func TestClient_HasPermissionWhenPermissionsAssignedDirectly(t *testing.T) {
+	client := NewClientWithPermissions("token", []string{"a", "b"})
+	if !client.isPermissionSetInSync() {
+		t.Error("expected the permission set to be in sync")
+	}
+	client.Permissions = []string{"c", "d"}
+	if client.isPermissionSetInSync() {
+		t.Error("expected the permission set to not be in sync after Permissions was assigned directly")
+	}
+	if client.HasPermission("a") || !client.HasPermission("c") {
+		t.Errorf("client has permissions %s, therefore HasPermission(a) should've been false and HasPermission(c) true", client.Permissions)
+	}
+	client.WithPermission("e")
+	if !client.isPermissionSetInSync() {
+		t.Error("expected the permission set to have been rebuilt")
+	}
+	if client.HasPermission("a") || !client.HasPermission("c") || !client.HasPermission("e") {
+		t.Errorf("client has permissions %s, therefore HasPermission(a) should've been false and HasPermission(c) and HasPermission(e) true", client.Permissions)
+	}
+	// Clients created without WithPermission or WithPermissions, such as those returned by a client provider, must
+	// still work
+	client = &Client{Token: "token", Permissions: []string{"repo:*"}}
+	if !client.HasPermission("repo:read") || client.HasPermission("billing:read") {
+		t.Errorf("client has permissions %s, therefore HasPermission(repo:read) should've been true and HasPermission(billing:read) false", client.Permissions)
+	}
+}
+
+func TestClient_HasPermissionWhenPermissionIsRevokedByReassigningPermissions(t *testing.T) {
+	client := NewClient("token").WithPermissions([]string{"a"}).WithPermission("b").WithPermission("c")
+	// Reassigning Permissions to a new slice of the same length must be detected, otherwise the revoked permission
+	// would still be granted
+	client.Permissions = slices.DeleteFunc(slices.Clone(client.Permissions), func(permission string) bool {
+		return permission == "b"
+	})
+	client.Permissions = append(client.Permissions, "admin")
+	if client.isPermissionSetInSync() {
+		t.Error("expected the permission set to not be in sync after Permissions was reassigned")
+	}
+	if client.HasPermission("b") || !client.HasPermission("a") || !client.HasPermission("c") || !client.HasPermission("admin") {
+		t.Errorf("client has permissions %s, therefore HasPermission(b) should've been false and HasPermission(a), HasPermission(c) and HasPermission(admin) true", client.Permissions)
+	}
+	// Reassigning Permissions to a shorter slice of the same underlying array must be detected as well
+	client.WithPermission("d")
+	client.Permissions = client.Permissions[:2]
+	if client.isPermissionSetInSync() {
+		t.Error("expected the permission set to not be in sync after Permissions was reassigned")
+	}
+	if client.HasPermission("admin") || client.HasPermission("d") || !client.HasPermission("c") {
+		t.Errorf("client has permissions %s, therefore HasPermission(admin) and HasPermission(d) should've been false and HasPermission(c) true", client.Permissions)
+	}
+}
+
+func TestClient_SetPermissions(t *testing.T) {
+	client := NewClient("token").WithPermissions([]string{"a", "b"}).SetPermissions([]string{"c"})
+	if !slices.Equal(client.Permissions, []string{"c"}) || !client.isPermissionSetInSync() {
+		t.Errorf("expected permissions to be [c] and indexed, got %v", client.Permissions)
+	}
+	if client.HasPermission("a") || !client.HasPermission("c") {
+		t.Errorf("client has permissions %s, therefore HasPermission(a) should've been false and HasPermission(c) true", client.Permissions)
+	}
+}
+
+func TestClient_RevokePermission(t *testing.T) {
+	client := NewClient("token").WithPermissions([]string{"a", "b", "a"})
+	permissions := client.Permissions
+	client.RevokePermission("a")
+	if !slices.Equal(client.Permissions, []string{"b"}) || !client.isPermissionSetInSync() {
+		t.Errorf("expected permissions to be [b] and indexed, got %v", client.Permissions)
+	}
+	if client.HasPermission("a") || !client.HasPermission("b") {
+		t.Errorf("client has permissions %s, therefore HasPermission(a) should've been false and HasPermission(b) true", client.Permissions)
+	}
+	if !slices.Equal(permissions, []string{"a", "b", "a"}) {
+		t.Errorf("expected the previous slice of permissions to not have been modified, got %v", permissions)
+	}
+}
+
+func TestClient_HasPermissionIndexedAndNotIndexedAreEquivalent(t *testing.T) {
+	grantedPermissions := []string{"*", "repo:*", "repo:issues:*", "admin", "admin:users", "billing:invoices:read", "a:", "x::y"}
+	requiredPermissions := []string{"repo", "repo:read", "repo:issues", "repo:issues:write", "admin", "admin:", "admin:users:delete", "administrator", "billing", "billing:invoices", "billing:invoices:read", "billing:invoices:write", "a", "a::b", "x", "x:", "x::y", "x::y:z"}
+	for _, grantedPermission := range grantedPermissions {
+		indexedClient := NewClient("token").WithPermission(grantedPermission)
+		notIndexedClient := &Client{Token: "token", Permissions: []string{grantedPermission}}
+		for _, requiredPermission := range requiredPermissions {
+			if indexed, notIndexed := indexedClient.HasPermission(requiredPermission), notIndexedClient.HasPermission(requiredPermission); indexed != notIndexed {
+				t.Errorf("expected HasPermission(%s) to be %v for a client with the permission %s, got %v", requiredPermission, notIndexed, grantedPermission, indexed)
+			}
+		}
+	}
+}
+
+func TestClient_WithExpiresAtAndNotBefore(t *testing.T) {
+	now := time.Now()
+	client := NewClient("token").WithNotBefore(now).WithExpiresAt(now.Add(time.Hour))
+	scenarios := []struct {
+		name                string
+		time                time.Time
+		expectedExpired     bool
+		expectedNotYetValid bool
+	}{
+		{name: "before-not-before", time: now.Add(-time.Second), expectedExpired: false, expectedNotYetValid: true},
+		{name: "at-not-before", time: now, expectedExpired: false, expectedNotYetValid: false},
+		{name: "between", time: now.Add(30 * time.Minute), expectedExpired: false, expectedNotYetValid: false},
+		{name: "at-expires-at", time: now.Add(time.Hour), expectedExpired: true, expectedNotYetValid: false},
+		{name: "after-expires-at", time: now.Add(2 * time.Hour), expectedExpired: true, expectedNotYetValid: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if expired := client.isExpired(scenario.time); expired != scenario.expectedExpired {
+				t.Errorf("expected isExpired to be %v, got %v", scenario.expectedExpired, expired)
+			}
+			if notYetValid := client.isNotYetValid(scenario.time); notYetValid != scenario.expectedNotYetValid {
+				t.Errorf("expected isNotYetValid to be %v, got %v", scenario.expectedNotYetValid, notYetValid)
+			}
+		})
+	}
+	// A client without ExpiresAt and NotBefore is always valid
+	if client := NewClient("token"); client.isExpired(now) || client.isNotYetValid(now) {
+		t.Error("expected client without ExpiresAt and NotBefore to be valid")
+	}
+}
@@ -0,0 +1,133 @@
+package g8
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	// ForwardedHeader is the standardized header (RFC 7239) used by proxies to disclose the address of the client
+	ForwardedHeader = "Forwarded"
+
+	// XForwardedForHeader is the de-facto standard header used by proxies to disclose the address of the client
+	XForwardedForHeader = "X-Forwarded-For"
+)
+
+// parseTrustedProxies parses a slice of CIDRs (e.g. 10.0.0.0/8) or IP addresses (e.g. 10.0.0.1) into prefixes
+func parseTrustedProxies(trustedProxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, trustedProxy := range trustedProxies {
+		if !strings.Contains(trustedProxy, "/") {
+			addr, err := netip.ParseAddr(trustedProxy)
+			if err != nil {
+				return nil, err
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(trustedProxy)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// isTrustedProxy checks whether an address is part of the trusted proxies configured using WithTrustedProxies
+func (gate *Gate) isTrustedProxy(addr netip.Addr) bool {
+	for _, trustedProxy := range gate.trustedProxies {
+		if trustedProxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractIPFromRequest extracts the IP address of the client that sent a request.
+//
+// By default, the IP address is extracted from the request's RemoteAddr. If the RemoteAddr is part of the trusted
+// proxies configured through WithTrustedProxies, however, the addresses disclosed by the header set through
+// WithTrustedProxyHeader (X-Forwarded-For by default) are inspected from right to left, and the first address that is
+// not a trusted proxy is returned. Other headers are ignored, since the proxies may pass them through unchanged.
+//
+// Note that this method is internally used by the per-IP rate limiting configured through WithIPRateLimit, but it is
+// exposed in case you need to use it directly.
+func (gate *Gate) ExtractIPFromRequest(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		// RemoteAddr may not have a port, in which case we'll assume that it's just an IP address
+		host = request.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	addr = addr.Unmap()
+	if !gate.isTrustedProxy(addr) {
+		return addr.String()
+	}
+	forwardedAddresses := extractForwardedAddresses(request, gate.trustedProxyHeader)
+	for i := len(forwardedAddresses) - 1; i >= 0; i-- {
+		forwardedAddr, err := netip.ParseAddr(forwardedAddresses[i])
+		if err != nil {
+			// The address is not a valid IP address (e.g. an obfuscated identifier), but since it was disclosed by a
+			// trusted proxy, it is still the best identifier we have for the client
+			return forwardedAddresses[i]
+		}
+		addr = forwardedAddr.Unmap()
+		if !gate.isTrustedProxy(addr) {
+			return addr.String()
+		}
+	}
+	// Every address in the chain is a trusted proxy, so the left-most one is the closest we have to the client
+	return addr.String()
+}
+
+// extractForwardedAddresses extracts the chain of addresses disclosed by proxies through the given header, ordered
+// from the client to the proxy closest to the server.
+//
+// If the header is the Forwarded header, the addresses are extracted from the "for" parameters. Otherwise, the header
+// is expected to be a comma-separated list of addresses, like X-Forwarded-For.
+func extractForwardedAddresses(request *http.Request, header string) []string {
+	var addresses []string
+	if strings.EqualFold(header, ForwardedHeader) {
+		for _, forwardedHeaderValue := range request.Header.Values(ForwardedHeader) {
+			for _, forwardedElement := range strings.Split(forwardedHeaderValue, ",") {
+				for _, pair := range strings.Split(forwardedElement, ";") {
+					key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+					if !found || !strings.EqualFold(key, "for") {
+						continue
+					}
+					addresses = append(addresses, stripPort(strings.Trim(value, "\"")))
+				}
+			}
+		}
+		return addresses
+	}
+	for _, headerValue := range request.Header.Values(header) {
+		for _, address := range strings.Split(headerValue, ",") {
+			if address = strings.TrimSpace(address); len(address) > 0 {
+				addresses = append(addresses, stripPort(address))
+			}
+		}
+	}
+	return addresses
+}
+
+// stripPort removes the port and the brackets from an address, if present.
+//
+// For instance, both "[2001:db8::1]:4711" and "[2001:db8::1]" become "2001:db8::1", and "192.0.2.1:80" becomes
+// "192.0.2.1".
+func stripPort(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+}
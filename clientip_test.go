@@ -0,0 +1,171 @@
+package g8
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGate_ExtractIPFromRequest(t *testing.T) {
+	scenarios := []struct {
+		name               string
+		trustedProxies     []string
+		trustedProxyHeader string
+		remoteAddr         string
+		headers            map[string][]string
+		expectedIP         string
+	}{
+		{
+			name:       "remote-addr",
+			remoteAddr: "192.0.2.1:1234",
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "remote-addr-without-port",
+			remoteAddr: "192.0.2.1",
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "remote-addr-ipv6",
+			remoteAddr: "[2001:db8::1]:1234",
+			expectedIP: "2001:db8::1",
+		},
+		{
+			name:       "remote-addr-ipv4-mapped-ipv6",
+			remoteAddr: "[::ffff:192.0.2.1]:1234",
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "x-forwarded-for-ignored-when-no-trusted-proxies",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string][]string{XForwardedForHeader: {"203.0.113.1"}},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:           "x-forwarded-for-ignored-when-remote-addr-is-not-trusted",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "192.0.2.1:1234",
+			headers:        map[string][]string{XForwardedForHeader: {"203.0.113.1"}},
+			expectedIP:     "192.0.2.1",
+		},
+		{
+			name:           "x-forwarded-for",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{XForwardedForHeader: {"203.0.113.1"}},
+			expectedIP:     "203.0.113.1",
+		},
+		{
+			name:           "x-forwarded-for-with-spoofed-address",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{XForwardedForHeader: {"198.51.100.1, 203.0.113.1, 10.0.0.2"}},
+			expectedIP:     "203.0.113.1",
+		},
+		{
+			name:           "x-forwarded-for-with-multiple-headers",
+			trustedProxies: []string{"10.0.0.1", "10.0.0.2"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{XForwardedForHeader: {"203.0.113.1", "10.0.0.2"}},
+			expectedIP:     "203.0.113.1",
+		},
+		{
+			name:           "x-forwarded-for-with-only-trusted-proxies",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{XForwardedForHeader: {"10.0.0.3, 10.0.0.2"}},
+			expectedIP:     "10.0.0.3",
+		},
+		{
+			name:           "forwarded-ignored-by-default",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers: map[string][]string{
+				ForwardedHeader:     {"for=9.9.9.9"},
+				XForwardedForHeader: {"1.2.3.4"},
+			},
+			expectedIP: "1.2.3.4",
+		},
+		{
+			name:               "forwarded",
+			trustedProxies:     []string{"10.0.0.0/8"},
+			trustedProxyHeader: ForwardedHeader,
+			remoteAddr:         "10.0.0.1:1234",
+			headers:            map[string][]string{ForwardedHeader: {`for=198.51.100.1, for="[2001:db8:cafe::17]:4711";proto=https`}},
+			expectedIP:         "2001:db8:cafe::17",
+		},
+		{
+			name:               "x-forwarded-for-ignored-when-forwarded-is-trusted",
+			trustedProxies:     []string{"10.0.0.0/8"},
+			trustedProxyHeader: ForwardedHeader,
+			remoteAddr:         "10.0.0.1:1234",
+			headers: map[string][]string{
+				ForwardedHeader:     {"For=203.0.113.1;by=10.0.0.1"},
+				XForwardedForHeader: {"198.51.100.1"},
+			},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:               "forwarded-missing-when-forwarded-is-trusted",
+			trustedProxies:     []string{"10.0.0.0/8"},
+			trustedProxyHeader: ForwardedHeader,
+			remoteAddr:         "10.0.0.1:1234",
+			headers:            map[string][]string{XForwardedForHeader: {"198.51.100.1"}},
+			expectedIP:         "10.0.0.1",
+		},
+		{
+			name:               "forwarded-with-obfuscated-identifier",
+			trustedProxies:     []string{"10.0.0.0/8"},
+			trustedProxyHeader: ForwardedHeader,
+			remoteAddr:         "10.0.0.1:1234",
+			headers:            map[string][]string{ForwardedHeader: {"for=_hidden"}},
+			expectedIP:         "_hidden",
+		},
+		{
+			name:               "custom-header",
+			trustedProxies:     []string{"10.0.0.0/8"},
+			trustedProxyHeader: "X-Real-IP",
+			remoteAddr:         "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Real-IP":         {"203.0.113.1"},
+				XForwardedForHeader: {"198.51.100.1"},
+			},
+			expectedIP: "203.0.113.1",
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			gate := New().WithTrustedProxies(scenario.trustedProxies)
+			if len(scenario.trustedProxyHeader) > 0 {
+				gate.WithTrustedProxyHeader(scenario.trustedProxyHeader)
+			}
+			request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+			request.RemoteAddr = scenario.remoteAddr
+			for key, values := range scenario.headers {
+				for _, value := range values {
+					request.Header.Add(key, value)
+				}
+			}
+			if ip := gate.ExtractIPFromRequest(request); ip != scenario.expectedIP {
+				t.Errorf("expected %s, got %s", scenario.expectedIP, ip)
+			}
+		})
+	}
+}
+
+func TestGate_WithTrustedProxiesWithInvalidProxy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithTrustedProxies to panic")
+		}
+	}()
+	New().WithTrustedProxies([]string{"not-an-ip"})
+}
+
+func TestGate_WithTrustedProxyHeaderWithEmptyHeader(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithTrustedProxyHeader to panic")
+		}
+	}()
+	New().WithTrustedProxyHeader("")
+}
@@ -0,0 +1,53 @@
+package g8
+
+import (
+	"sync"
+)
+
+// concurrencyLimiter keeps track of the number of requests in flight for each key and prevents said number from
+// exceeding a maximum.
+//
+// Keys are removed as soon as they have no requests in flight, so memory usage is bounded by the number of requests
+// in flight.
+type concurrencyLimiter struct {
+	maximumConcurrentRequests int
+	inFlight                  map[string]int
+	mutex                     sync.Mutex
+}
+
+// newConcurrencyLimiter creates a concurrencyLimiter
+//
+// Panics if maximumConcurrentRequests is not positive, since every request would be rejected.
+func newConcurrencyLimiter(maximumConcurrentRequests int) *concurrencyLimiter {
+	if maximumConcurrentRequests <= 0 {
+		panic("g8: maximum concurrent requests must be positive")
+	}
+	return &concurrencyLimiter{
+		maximumConcurrentRequests: maximumConcurrentRequests,
+		inFlight:                  make(map[string]int),
+	}
+}
+
+// acquire reserves a slot for a request associated with the given key and returns whether a slot was available.
+//
+// If true is returned, release must be called with the same key once the request has been handled.
+func (limiter *concurrencyLimiter) acquire(key string) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.inFlight[key] >= limiter.maximumConcurrentRequests {
+		return false
+	}
+	limiter.inFlight[key]++
+	return true
+}
+
+// release frees a slot previously reserved through acquire
+func (limiter *concurrencyLimiter) release(key string) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.inFlight[key] <= 1 {
+		delete(limiter.inFlight, key)
+		return
+	}
+	limiter.inFlight[key]--
+}
@@ -0,0 +1,60 @@
+package g8
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNewConcurrencyLimiterWithInvalidMaximum(t *testing.T) {
+	for _, maximumConcurrentRequests := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected newConcurrencyLimiter to panic with %d", maximumConcurrentRequests)
+				}
+			}()
+			newConcurrencyLimiter(maximumConcurrentRequests)
+		}()
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := newConcurrencyLimiter(2)
+	if !limiter.acquire("a") || !limiter.acquire("a") {
+		t.Fatal("expected to acquire a slot")
+	}
+	if limiter.acquire("a") {
+		t.Error("expected to not acquire a slot, since the maximum has been reached")
+	}
+	if !limiter.acquire("b") {
+		t.Error("expected to acquire a slot, since each key has its own slots")
+	}
+	limiter.release("a")
+	if !limiter.acquire("a") {
+		t.Error("expected to acquire a slot, since one was released")
+	}
+	limiter.release("a")
+	limiter.release("a")
+	limiter.release("b")
+	if len(limiter.inFlight) != 0 {
+		t.Errorf("expected keys without requests in flight to be removed, got %v", limiter.inFlight)
+	}
+}
+
+func TestConcurrencyLimiter_Concurrently(t *testing.T) {
+	limiter := newConcurrencyLimiter(5)
+	waitGroup := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if limiter.acquire("key") {
+				limiter.release("key")
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if len(limiter.inFlight) != 0 {
+		t.Errorf("expected every slot to have been released, got %v", limiter.inFlight)
+	}
+}
@@ -0,0 +1,66 @@
+package g8
+
+import "context"
+
+// contextKey is the type of the keys used by Gate to store values in the context of the requests it lets through,
+// which prevents collisions with keys defined by other packages
+type contextKey int
+
+const (
+	// tokenContextKey is the key used to store the client's token in the context
+	tokenContextKey contextKey = iota
+
+	// clientContextKey is the key used to store the client in the context
+	clientContextKey
+)
+
+// newContextWithClient returns a copy of ctx in which the token and the client that was authorized are stored.
+//
+// The token and the client's data are also stored under the deprecated TokenContextKey and DataContextKey, so that
+// handlers that have yet to migrate to TokenFromContext and DataFromContext keep working.
+func newContextWithClient(ctx context.Context, token string, client *Client) context.Context {
+	ctx = context.WithValue(ctx, tokenContextKey, token)
+	ctx = context.WithValue(ctx, TokenContextKey, token)
+	if client != nil {
+		ctx = context.WithValue(ctx, clientContextKey, client)
+		if client.Data != nil {
+			ctx = context.WithValue(ctx, DataContextKey, client.Data)
+		}
+	}
+	return ctx
+}
+
+// TokenFromContext returns the token extracted by Gate from the request whose context is passed as parameter, as well
+// as whether there is one.
+//
+//	router.Handle("/handle", gate.ProtectFunc(func(w http.ResponseWriter, r *http.Request) {
+//		token, _ := g8.TokenFromContext(r.Context())
+//		// ...
+//	}))
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenContextKey).(string)
+	return token, ok
+}
+
+// ClientFromContext returns the Client that Gate authorized for the request whose context is passed as parameter, as
+// well as whether there is one.
+//
+// The Client returned may be shared with other requests, so it must not be modified.
+func ClientFromContext(ctx context.Context) (*Client, bool) {
+	client, ok := ctx.Value(clientContextKey).(*Client)
+	return client, ok
+}
+
+// DataFromContext returns the Data of the Client that Gate authorized for the request whose context is passed as
+// parameter, as well as whether there is one of type T.
+//
+//	user, ok := g8.DataFromContext[*User](r.Context())
+func DataFromContext[T any](ctx context.Context) (T, bool) {
+	client, ok := ClientFromContext(ctx)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	data, ok := client.Data.(T)
+	return data, ok
+}
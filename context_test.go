@@ -0,0 +1,70 @@
+package g8
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewContextWithClient(t *testing.T) {
+	client := NewClientWithData("token", map[string]string{"name": "john"})
+	ctx := newContextWithClient(context.Background(), "token", client)
+	if token, ok := TokenFromContext(ctx); !ok || token != "token" {
+		t.Errorf("expected token to be %s, got %s", "token", token)
+	}
+	if clientFromContext, ok := ClientFromContext(ctx); !ok || clientFromContext != client {
+		t.Errorf("expected client to be %v, got %v", client, clientFromContext)
+	}
+	if data, ok := DataFromContext[map[string]string](ctx); !ok || data["name"] != "john" {
+		t.Errorf("expected data to be %v, got %v", client.Data, data)
+	}
+	if data, ok := DataFromContext[string](ctx); ok || data != "" {
+		t.Errorf("expected no data of type string, got %s", data)
+	}
+}
+
+func TestNewContextWithClientWithDeprecatedContextKeys(t *testing.T) {
+	client := NewClientWithData("token", "data")
+	ctx := newContextWithClient(context.Background(), "token", client)
+	if token := ctx.Value(TokenContextKey); token != "token" {
+		t.Errorf("expected token to be %s, got %v", "token", token)
+	}
+	if data := ctx.Value(DataContextKey); data != "data" {
+		t.Errorf("expected data to be %s, got %v", "data", data)
+	}
+	// Clients without data must not have anything stored under DataContextKey
+	ctx = newContextWithClient(context.Background(), "token", NewClient("token"))
+	if data := ctx.Value(DataContextKey); data != nil {
+		t.Errorf("expected no data, got %v", data)
+	}
+}
+
+func TestNewContextWithClientWithoutClient(t *testing.T) {
+	ctx := newContextWithClient(context.Background(), "token", nil)
+	if token, ok := TokenFromContext(ctx); !ok || token != "token" {
+		t.Errorf("expected token to be %s, got %s", "token", token)
+	}
+	if client, ok := ClientFromContext(ctx); ok || client != nil {
+		t.Errorf("expected no client, got %v", client)
+	}
+	if data, ok := DataFromContext[any](ctx); ok || data != nil {
+		t.Errorf("expected no data, got %v", data)
+	}
+}
+
+func TestFromContextWithEmptyContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := TokenFromContext(ctx); ok {
+		t.Error("expected no token")
+	}
+	if _, ok := ClientFromContext(ctx); ok {
+		t.Error("expected no client")
+	}
+	if _, ok := DataFromContext[string](ctx); ok {
+		t.Error("expected no data")
+	}
+	// A string key with the same value as an unexported key must not collide with it
+	ctx = context.WithValue(ctx, "g8.token", "token")
+	if _, ok := TokenFromContext(ctx); ok {
+		t.Error("expected no token")
+	}
+}
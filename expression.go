@@ -0,0 +1,237 @@
+package g8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpressionError is the error returned by ParseRequirement if an expression is invalid
+type ExpressionError struct {
+	// Expression is the expression that failed to be parsed
+	Expression string
+
+	// Position is the position, starting at 1, of the character at which the error was detected.
+	// If the error was detected at the end of the expression, it is equal to the length of the expression + 1.
+	Position int
+
+	// Message is a description of the error
+	Message string
+}
+
+// Error returns a description of the error, including the expression and the position at which it was detected
+func (err *ExpressionError) Error() string {
+	return fmt.Sprintf("invalid permission expression %q: %s at position %d", err.Expression, err.Message, err.Position)
+}
+
+// expressionTokenKind is the kind of an expressionToken
+type expressionTokenKind int
+
+const (
+	expressionTokenPermission expressionTokenKind = iota
+	expressionTokenAnd
+	expressionTokenOr
+	expressionTokenNot
+	expressionTokenLeftParenthesis
+	expressionTokenRightParenthesis
+	expressionTokenEnd
+)
+
+// expressionToken is a token of a permission expression
+type expressionToken struct {
+	kind     expressionTokenKind
+	value    string
+	position int
+}
+
+// describe returns a description of the token for use in error messages
+func (token expressionToken) describe() string {
+	if token.kind == expressionTokenEnd {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", token.value)
+}
+
+// expressionParser is a recursive descent parser for permission expressions
+type expressionParser struct {
+	expression string
+	tokens     []expressionToken
+	current    int
+}
+
+// ParseRequirement parses a boolean permission expression into a Requirement.
+//
+// An expression is made of permissions combined with the operators && (and), || (or) and ! (not), as well as
+// parentheses for grouping. As is usually the case, ! has precedence over &&, which has precedence over ||.
+// For instance:
+//
+//	requirement, err := g8.ParseRequirement("(admin || (billing && read)) && !suspended")
+//
+// A permission may contain any character other than whitespace, parentheses, !, & and |.
+//
+// As with Not, permissions negated by ! are only matched against the permissions explicitly granted to a client, so a
+// client with PermissionWildcard satisfies the expression above as long as it was not granted "suspended" explicitly.
+//
+// The expression is parsed once, and the resulting Requirement is cheap to evaluate. If the expression is invalid, an
+// *ExpressionError describing the problem and its position is returned.
+func ParseRequirement(expression string) (Requirement, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &expressionParser{expression: expression, tokens: tokens}
+	requirement, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != expressionTokenEnd {
+		if token.kind == expressionTokenRightParenthesis {
+			return nil, parser.errorAt(token, "unexpected \")\" without matching \"(\"")
+		}
+		return nil, parser.errorAt(token, "expected operator but got "+token.describe())
+	}
+	return requirement, nil
+}
+
+// MustParseRequirement does the same thing as ParseRequirement, but panics if the expression is invalid.
+//
+// This is meant to be used when the expression is known at startup, so that an invalid expression prevents the
+// application from starting rather than causing every request to be rejected.
+func MustParseRequirement(expression string) Requirement {
+	requirement, err := ParseRequirement(expression)
+	if err != nil {
+		panic("g8: " + err.Error())
+	}
+	return requirement
+}
+
+// tokenizeExpression splits an expression into tokens
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	for i := 0; i < len(expression); {
+		switch character := expression[i]; {
+		case isExpressionWhitespace(character):
+			i++
+		case character == '(':
+			tokens = append(tokens, expressionToken{kind: expressionTokenLeftParenthesis, value: "(", position: i + 1})
+			i++
+		case character == ')':
+			tokens = append(tokens, expressionToken{kind: expressionTokenRightParenthesis, value: ")", position: i + 1})
+			i++
+		case character == '!':
+			tokens = append(tokens, expressionToken{kind: expressionTokenNot, value: "!", position: i + 1})
+			i++
+		case character == '&' || character == '|':
+			if i+1 >= len(expression) || expression[i+1] != character {
+				return nil, &ExpressionError{Expression: expression, Position: i + 1, Message: fmt.Sprintf("expected %q", strings.Repeat(string(character), 2))}
+			}
+			kind := expressionTokenAnd
+			if character == '|' {
+				kind = expressionTokenOr
+			}
+			tokens = append(tokens, expressionToken{kind: kind, value: expression[i : i+2], position: i + 1})
+			i += 2
+		default:
+			start := i
+			for i < len(expression) && !isExpressionWhitespace(expression[i]) && !strings.ContainsRune("()!&|", rune(expression[i])) {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: expressionTokenPermission, value: expression[start:i], position: start + 1})
+		}
+	}
+	return append(tokens, expressionToken{kind: expressionTokenEnd, position: len(expression) + 1}), nil
+}
+
+// isExpressionWhitespace checks whether a byte of an expression is whitespace, which separates tokens.
+//
+// Only ASCII whitespace is considered, since expressions are tokenized byte by byte, and bytes of multibyte UTF-8
+// characters used in permissions must not be mistaken for whitespace.
+func isExpressionWhitespace(character byte) bool {
+	switch character {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// parseOr parses a sequence of one or more operands separated by ||
+func (parser *expressionParser) parseOr() (Requirement, error) {
+	var requirements anyOfRequirement
+	for {
+		requirement, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, requirement)
+		if parser.peek().kind != expressionTokenOr {
+			break
+		}
+		parser.current++
+	}
+	if len(requirements) == 1 {
+		return requirements[0], nil
+	}
+	return requirements, nil
+}
+
+// parseAnd parses a sequence of one or more operands separated by &&
+func (parser *expressionParser) parseAnd() (Requirement, error) {
+	var requirements allOfRequirement
+	for {
+		requirement, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, requirement)
+		if parser.peek().kind != expressionTokenAnd {
+			break
+		}
+		parser.current++
+	}
+	if len(requirements) == 1 {
+		return requirements[0], nil
+	}
+	return requirements, nil
+}
+
+// parseUnary parses a permission, a negated operand or a parenthesized expression
+func (parser *expressionParser) parseUnary() (Requirement, error) {
+	token := parser.peek()
+	switch token.kind {
+	case expressionTokenPermission:
+		parser.current++
+		return permissionRequirement(token.value), nil
+	case expressionTokenNot:
+		parser.current++
+		requirement, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notRequirement{requirement: requirement}, nil
+	case expressionTokenLeftParenthesis:
+		parser.current++
+		requirement, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closingToken := parser.peek(); closingToken.kind != expressionTokenRightParenthesis {
+			if closingToken.kind == expressionTokenEnd {
+				return nil, parser.errorAt(token, "unclosed \"(\"")
+			}
+			return nil, parser.errorAt(closingToken, "expected operator or \")\" but got "+closingToken.describe())
+		}
+		parser.current++
+		return requirement, nil
+	default:
+		return nil, parser.errorAt(token, "expected permission, \"!\" or \"(\" but got "+token.describe())
+	}
+}
+
+// peek returns the current token without consuming it
+func (parser *expressionParser) peek() expressionToken {
+	return parser.tokens[parser.current]
+}
+
+// errorAt creates an ExpressionError at the position of a token
+func (parser *expressionParser) errorAt(token expressionToken, message string) *ExpressionError {
+	return &ExpressionError{Expression: parser.expression, Position: token.position, Message: message}
+}
@@ -0,0 +1,110 @@
+package g8
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRequirement(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		expression  string
+		permissions []string
+		expected    bool
+	}{
+		{name: "permission", expression: "admin", permissions: []string{"admin"}, expected: true},
+		{name: "permission-missing", expression: "admin", permissions: []string{"read"}, expected: false},
+		{name: "permission-with-special-characters", expression: "org:billing.read-write/*", permissions: []string{"org:billing.read-write/*"}, expected: true},
+		{name: "and", expression: "billing && read", permissions: []string{"billing", "read"}, expected: true},
+		{name: "and-with-missing-permission", expression: "billing && read", permissions: []string{"billing"}, expected: false},
+		{name: "or", expression: "admin || support", permissions: []string{"support"}, expected: true},
+		{name: "or-with-missing-permissions", expression: "admin || support", permissions: []string{"read"}, expected: false},
+		{name: "not", expression: "!suspended", permissions: nil, expected: true},
+		{name: "not-with-permission", expression: "!suspended", permissions: []string{"suspended"}, expected: false},
+		{name: "double-not", expression: "!!admin", permissions: []string{"admin"}, expected: true},
+		{name: "and-has-precedence-over-or", expression: "admin || billing && read", permissions: []string{"admin"}, expected: true},
+		{name: "not-has-precedence-over-and", expression: "!suspended && read", permissions: []string{"read"}, expected: true},
+		{name: "parentheses", expression: "(admin || billing) && read", permissions: []string{"admin"}, expected: false},
+		{name: "with-other-whitespace", expression: "\vadmin\f||\v\tbilling\r\n", permissions: []string{"billing"}, expected: true},
+		{name: "with-multibyte-characters", expression: "données:à-jour", permissions: []string{"données:à-jour"}, expected: true},
+		{name: "without-whitespace", expression: "(admin||billing)&&!suspended", permissions: []string{"billing"}, expected: true},
+		{name: "complex", expression: "(admin || (billing && read)) && !suspended", permissions: []string{"billing", "read"}, expected: true},
+		{name: "complex-with-suspended", expression: "(admin || (billing && read)) && !suspended", permissions: []string{"admin", "suspended"}, expected: false},
+		{name: "complex-with-missing-permission", expression: "(admin || (billing && read)) && !suspended", permissions: []string{"billing"}, expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			requirement, err := ParseRequirement(scenario.expression)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if satisfied := requirement.IsSatisfiedBy(NewClient("token").WithPermissions(scenario.permissions)); satisfied != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, satisfied)
+			}
+		})
+	}
+}
+
+func TestParseRequirementWithInvalidExpression(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		expression       string
+		expectedPosition int
+		expectedError    string
+	}{
+		{name: "empty", expression: "", expectedPosition: 1, expectedError: `invalid permission expression "": expected permission, "!" or "(" but got end of expression at position 1`},
+		{name: "whitespace", expression: "   ", expectedPosition: 4},
+		{name: "single-ampersand", expression: "admin & read", expectedPosition: 7, expectedError: `invalid permission expression "admin & read": expected "&&" at position 7`},
+		{name: "single-pipe", expression: "admin | read", expectedPosition: 7},
+		{name: "missing-operand", expression: "admin &&", expectedPosition: 9},
+		{name: "missing-operator", expression: "admin read", expectedPosition: 7, expectedError: `invalid permission expression "admin read": expected operator but got "read" at position 7`},
+		{name: "leading-operator", expression: "|| admin", expectedPosition: 1},
+		{name: "unclosed-parenthesis", expression: "(admin || read", expectedPosition: 1, expectedError: `invalid permission expression "(admin || read": unclosed "(" at position 1`},
+		{name: "unopened-parenthesis", expression: "admin || read)", expectedPosition: 14},
+		{name: "empty-parentheses", expression: "admin && ()", expectedPosition: 11},
+		{name: "not-without-operand", expression: "admin && !", expectedPosition: 11},
+		{name: "missing-operator-after-parentheses", expression: "(admin) read", expectedPosition: 9},
+		{name: "missing-operator-inside-parentheses", expression: "(admin read)", expectedPosition: 8},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			requirement, err := ParseRequirement(scenario.expression)
+			if requirement != nil {
+				t.Error("expected requirement to be nil")
+			}
+			var expressionError *ExpressionError
+			if !errors.As(err, &expressionError) {
+				t.Fatalf("expected an *ExpressionError, got %v", err)
+			}
+			if expressionError.Position != scenario.expectedPosition {
+				t.Errorf("expected position to be %d, got %d (%v)", scenario.expectedPosition, expressionError.Position, err)
+			}
+			if len(scenario.expectedError) > 0 && err.Error() != scenario.expectedError {
+				t.Errorf("expected error to be %s, got %s", scenario.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestTokenizeExpressionWithWhitespace(t *testing.T) {
+	// Whitespace must both be skipped between tokens and end permissions, regardless of its kind
+	tokens, err := tokenizeExpression("\va\vb\f")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(tokens) != 3 || tokens[0].value != "a" || tokens[1].value != "b" || tokens[2].kind != expressionTokenEnd {
+		t.Errorf("expected tokens a and b followed by the end of the expression, got %+v", tokens)
+	}
+}
+
+func TestMustParseRequirement(t *testing.T) {
+	if requirement := MustParseRequirement("admin || support"); requirement == nil {
+		t.Error("expected requirement to not be nil")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MustParseRequirement to panic")
+		}
+	}()
+	MustParseRequirement("admin ||")
+}
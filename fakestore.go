@@ -0,0 +1,89 @@
+package g8
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeStore is an in-memory implementation of Store meant to be used as a test double.
+//
+// Unlike MemoryStore, counters never expire, which makes tests deterministic, the calls made to it are recorded, and it
+// can be made to return an error, which lets you test how your application behaves when your actual Store (e.g. one
+// backed by Redis) is unavailable:
+//
+//	store := g8.NewFakeStore().WithError(errors.New("connection refused"))
+//	rateLimiter := g8.NewStoreRateLimiter(store, "my-api", 100, time.Second)
+type FakeStore struct {
+	counters map[string]int
+	calls    int
+	err      error
+
+	mutex sync.Mutex
+}
+
+// NewFakeStore creates a FakeStore
+func NewFakeStore() *FakeStore {
+	return &FakeStore{
+		counters: make(map[string]int),
+	}
+}
+
+// WithError makes every subsequent call to Increment and Get return err without modifying any counter.
+// Passing nil makes the FakeStore work normally again.
+func (store *FakeStore) WithError(err error) *FakeStore {
+	store.mutex.Lock()
+	store.err = err
+	store.mutex.Unlock()
+	return store
+}
+
+// Increment increments the counter associated with a key by delta and returns the updated value of the counter.
+//
+// Since counters never expire, the time left before the counter expires is always the expiration passed as parameter.
+func (store *FakeStore) Increment(key string, delta int, expiration time.Duration) (int, time.Duration, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.calls++
+	if store.err != nil {
+		return 0, 0, store.err
+	}
+	store.counters[key] += delta
+	return store.counters[key], expiration, nil
+}
+
+// Get returns the value of the counter associated with a key, or 0 if there is none
+func (store *FakeStore) Get(key string) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.calls++
+	if store.err != nil {
+		return 0, store.err
+	}
+	return store.counters[key], nil
+}
+
+// Count returns the value of the counter associated with a key.
+//
+// Unlike Get, it is not recorded as a call, and it ignores the error set through WithError.
+func (store *FakeStore) Count(key string) int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.counters[key]
+}
+
+// Len returns the number of counters in the FakeStore
+func (store *FakeStore) Len() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.counters)
+}
+
+// Calls returns the number of times Increment and Get have been called, including the calls that returned an error
+func (store *FakeStore) Calls() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.calls
+}
+
+// Make sure that FakeStore is compatible with the interface
+var _ Store = (*FakeStore)(nil)
@@ -0,0 +1,65 @@
+package g8
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeStore_Increment(t *testing.T) {
+	store := NewFakeStore()
+	if count, ttl, err := store.Increment("key", 2, time.Minute); err != nil || count != 2 || ttl != time.Minute {
+		t.Errorf("expected (2, 1m, nil), got (%d, %s, %v)", count, ttl, err)
+	}
+	if count, _, _ := store.Increment("key", -1, time.Minute); count != 1 {
+		t.Errorf("expected count to be 1, got %d", count)
+	}
+	store.Increment("other-key", 1, time.Minute)
+	if store.Count("key") != 1 || store.Count("other-key") != 1 || store.Count("missing-key") != 0 {
+		t.Errorf("unexpected counters %v", store.counters)
+	}
+	if store.Len() != 2 {
+		t.Errorf("expected %d counters, got %d", 2, store.Len())
+	}
+	if store.Calls() != 3 {
+		t.Errorf("expected %d calls, got %d", 3, store.Calls())
+	}
+}
+
+func TestFakeStore_Get(t *testing.T) {
+	store := NewFakeStore()
+	if count, err := store.Get("key"); err != nil || count != 0 {
+		t.Errorf("expected (0, nil), got (%d, %v)", count, err)
+	}
+	if store.Len() != 0 {
+		t.Error("expected Get to not create the counter")
+	}
+	store.Increment("key", 3, time.Minute)
+	if count, err := store.Get("key"); err != nil || count != 3 {
+		t.Errorf("expected (3, nil), got (%d, %v)", count, err)
+	}
+	if store.Calls() != 3 {
+		t.Errorf("expected %d calls, got %d", 3, store.Calls())
+	}
+}
+
+func TestFakeStore_WithError(t *testing.T) {
+	errStoreUnavailable := errors.New("store unavailable")
+	store := NewFakeStore().WithError(errStoreUnavailable)
+	if _, _, err := store.Increment("key", 1, time.Minute); !errors.Is(err, errStoreUnavailable) {
+		t.Errorf("expected error to be %v, got %v", errStoreUnavailable, err)
+	}
+	if _, err := store.Get("key"); !errors.Is(err, errStoreUnavailable) {
+		t.Errorf("expected error to be %v, got %v", errStoreUnavailable, err)
+	}
+	if store.Count("key") != 0 {
+		t.Error("expected the counter to not have been incremented")
+	}
+	store.WithError(nil)
+	if count, _, err := store.Increment("key", 1, time.Minute); err != nil || count != 1 {
+		t.Errorf("expected (1, nil), got (%d, %v)", count, err)
+	}
+	if store.Calls() != 3 {
+		t.Errorf("expected %d calls, got %d", 3, store.Calls())
+	}
+}
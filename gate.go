@@ -2,8 +2,13 @@ package g8
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -13,41 +18,109 @@ const (
 	// DefaultUnauthorizedResponseBody is the default response body returned if a request was sent with a missing or invalid token
 	DefaultUnauthorizedResponseBody = "token is missing or invalid"
 
+	// DefaultForbiddenResponseBody is the default response body returned if a request was sent with a valid token that
+	// does not have the required permissions
+	DefaultForbiddenResponseBody = "insufficient permissions"
+
 	// DefaultTooManyRequestsResponseBody is the default response body returned if a request exceeded the allowed rate limit
 	DefaultTooManyRequestsResponseBody = "too many requests"
 
+	// DefaultTooManyConcurrentRequestsResponseBody is the default response body returned if a request exceeded the
+	// allowed number of concurrent requests
+	DefaultTooManyConcurrentRequestsResponseBody = "too many concurrent requests"
+
+	// DefaultQuotaExceededResponseBody is the default response body returned if a client has exhausted its quota
+	DefaultQuotaExceededResponseBody = "quota exceeded"
+
+	// DefaultMethodNotAllowedResponseBody is the default response body returned if the method of a request is not one
+	// of the methods of a handler protected with ProtectWithMethodPermissions
+	DefaultMethodNotAllowedResponseBody = "method not allowed"
+
 	// TokenContextKey is the key used to store the client's token in the context.
+	//
+	// Deprecated: Use TokenFromContext instead. The token is still stored under this key for backward compatibility.
 	TokenContextKey = "g8.token"
 
 	// DataContextKey is the key used to store the client's data in the context.
+	//
+	// Deprecated: Use DataFromContext instead. The client's data is still stored under this key for backward
+	// compatibility.
 	DataContextKey = "g8.data"
 )
 
+var (
+	// ErrTooManyRequests is the reason passed to the error handler if a request exceeded a rate limit
+	ErrTooManyRequests = errors.New("too many requests")
+
+	// ErrTooManyConcurrentRequests is the reason passed to the error handler if a request exceeded a concurrency limit
+	ErrTooManyConcurrentRequests = errors.New("too many concurrent requests")
+
+	// ErrQuotaExceeded is the reason passed to the error handler if the client has exhausted its quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrMethodNotAllowed is the reason passed to the error handler if the method of a request is not one of the
+	// methods of a handler protected with ProtectWithMethodPermissions
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
 // Gate is lock to the front door of your API, letting only those you allow through.
 type Gate struct {
 	authorizationService     *AuthorizationService
 	unauthorizedResponseBody []byte
+	forbiddenResponseBody    []byte
+	realm                    string
 
 	customTokenExtractorFunc func(request *http.Request) string
 
-	rateLimiter                 *RateLimiter
+	rateLimiter                 Limiter
+	clientRateLimiterPool       *rateLimiterPool
+	clientRateLimitFunc         func(client *Client) int
+	ipRateLimiterPool           *rateLimiterPool
+	rateLimitMaxWait            time.Duration
 	tooManyRequestsResponseBody []byte
+
+	concurrencyLimiter                    *concurrencyLimiter
+	clientConcurrencyLimiter              *concurrencyLimiter
+	tooManyConcurrentRequestsStatusCode   int
+	tooManyConcurrentRequestsResponseBody []byte
+
+	quotas                    []*Quota
+	quotaExceededResponseBody []byte
+
+	methodNotAllowedResponseBody []byte
+
+	trustedProxies     []netip.Prefix
+	trustedProxyHeader string
+
+	errorHandler func(writer http.ResponseWriter, request *http.Request, reason error)
 }
 
 // Deprecated: use New instead.
 func NewGate(authorizationService *AuthorizationService) *Gate {
 	return &Gate{
-		authorizationService:        authorizationService,
-		unauthorizedResponseBody:    []byte(DefaultUnauthorizedResponseBody),
-		tooManyRequestsResponseBody: []byte(DefaultTooManyRequestsResponseBody),
+		authorizationService:                  authorizationService,
+		unauthorizedResponseBody:              []byte(DefaultUnauthorizedResponseBody),
+		forbiddenResponseBody:                 []byte(DefaultForbiddenResponseBody),
+		tooManyRequestsResponseBody:           []byte(DefaultTooManyRequestsResponseBody),
+		tooManyConcurrentRequestsStatusCode:   http.StatusTooManyRequests,
+		tooManyConcurrentRequestsResponseBody: []byte(DefaultTooManyConcurrentRequestsResponseBody),
+		quotaExceededResponseBody:             []byte(DefaultQuotaExceededResponseBody),
+		methodNotAllowedResponseBody:          []byte(DefaultMethodNotAllowedResponseBody),
+		trustedProxyHeader:                    XForwardedForHeader,
 	}
 }
 
 // New creates a new Gate.
 func New() *Gate {
 	return &Gate{
-		unauthorizedResponseBody:    []byte(DefaultUnauthorizedResponseBody),
-		tooManyRequestsResponseBody: []byte(DefaultTooManyRequestsResponseBody),
+		unauthorizedResponseBody:              []byte(DefaultUnauthorizedResponseBody),
+		forbiddenResponseBody:                 []byte(DefaultForbiddenResponseBody),
+		tooManyRequestsResponseBody:           []byte(DefaultTooManyRequestsResponseBody),
+		tooManyConcurrentRequestsStatusCode:   http.StatusTooManyRequests,
+		tooManyConcurrentRequestsResponseBody: []byte(DefaultTooManyConcurrentRequestsResponseBody),
+		quotaExceededResponseBody:             []byte(DefaultQuotaExceededResponseBody),
+		methodNotAllowedResponseBody:          []byte(DefaultMethodNotAllowedResponseBody),
+		trustedProxyHeader:                    XForwardedForHeader,
 	}
 }
 
@@ -60,11 +133,91 @@ func (gate *Gate) WithAuthorizationService(authorizationService *AuthorizationSe
 }
 
 // WithCustomUnauthorizedResponseBody sets a custom response body when Gate determines that a request must be blocked
+// because its token is missing or invalid
 func (gate *Gate) WithCustomUnauthorizedResponseBody(unauthorizedResponseBody []byte) *Gate {
 	gate.unauthorizedResponseBody = unauthorizedResponseBody
 	return gate
 }
 
+// WithCustomForbiddenResponseBody sets a custom response body when Gate determines that a request must be blocked
+// because while its token is valid, the client does not have the required permissions
+func (gate *Gate) WithCustomForbiddenResponseBody(forbiddenResponseBody []byte) *Gate {
+	gate.forbiddenResponseBody = forbiddenResponseBody
+	return gate
+}
+
+// WithCustomQuotaExceededResponseBody sets a custom response body when Gate rejects a request because the client has
+// exhausted its quota
+func (gate *Gate) WithCustomQuotaExceededResponseBody(quotaExceededResponseBody []byte) *Gate {
+	gate.quotaExceededResponseBody = quotaExceededResponseBody
+	return gate
+}
+
+// WithCustomMethodNotAllowedResponseBody sets a custom response body when Gate rejects a request because its method
+// is not one of the methods of a handler protected with ProtectWithMethodPermissions
+func (gate *Gate) WithCustomMethodNotAllowedResponseBody(methodNotAllowedResponseBody []byte) *Gate {
+	gate.methodNotAllowedResponseBody = methodNotAllowedResponseBody
+	return gate
+}
+
+// WithCustomTooManyConcurrentRequestsResponseBody sets a custom response body when Gate rejects a request because of
+// WithConcurrencyLimit or WithClientConcurrencyLimit
+func (gate *Gate) WithCustomTooManyConcurrentRequestsResponseBody(tooManyConcurrentRequestsResponseBody []byte) *Gate {
+	gate.tooManyConcurrentRequestsResponseBody = tooManyConcurrentRequestsResponseBody
+	return gate
+}
+
+// WithRealm sets the realm included in the WWW-Authenticate header of requests rejected because their token is
+// missing or invalid, or because their client does not have the required permissions.
+//
+// For instance, with the following configuration, a request with an invalid token would be rejected with the header
+// WWW-Authenticate: Bearer realm="example", error="invalid_token"
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithRealm("example")
+//
+// If no realm is set, the realm parameter is omitted from the header.
+func (gate *Gate) WithRealm(realm string) *Gate {
+	gate.realm = realm
+	return gate
+}
+
+// WithErrorHandler sets a function responsible for writing the response to requests rejected by Gate, replacing the
+// default plain text responses, as well as the custom response bodies.
+//
+// The reason passed to the error handler is one of the following:
+//   - ErrMissingToken or ErrInvalidToken if the request should be rejected with a 401 Unauthorized
+//   - ErrInsufficientPermissions if the request should be rejected with a 403 Forbidden
+//   - ErrTooManyRequests or ErrQuotaExceeded if the request should be rejected with a 429 Too Many Requests
+//   - ErrTooManyConcurrentRequests if the request should be rejected with the status code set through
+//     WithConcurrencyLimitStatusCode (429 Too Many Requests by default)
+//   - ErrMethodNotAllowed if the request should be rejected with a 405 Method Not Allowed
+//     (see ProtectWithMethodPermissions)
+//
+// By the time the error handler is called, headers such as WWW-Authenticate, Allow, Retry-After and the RateLimit
+// headers have already been set, but the error handler is responsible for writing the status code and the body.
+//
+// For instance, to respond with JSON to clients that accept it:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithErrorHandler(func(writer http.ResponseWriter, request *http.Request, reason error) {
+//		status := http.StatusTooManyRequests
+//		if errors.Is(reason, g8.ErrMissingToken) || errors.Is(reason, g8.ErrInvalidToken) {
+//			status = http.StatusUnauthorized
+//		} else if errors.Is(reason, g8.ErrInsufficientPermissions) {
+//			status = http.StatusForbidden
+//		}
+//		if strings.Contains(request.Header.Get("Accept"), "application/json") {
+//			writer.Header().Set("Content-Type", "application/json")
+//			writer.WriteHeader(status)
+//			_ = json.NewEncoder(writer).Encode(map[string]string{"error": reason.Error()})
+//			return
+//		}
+//		http.Error(writer, reason.Error(), status)
+//	})
+func (gate *Gate) WithErrorHandler(errorHandler func(writer http.ResponseWriter, request *http.Request, reason error)) *Gate {
+	gate.errorHandler = errorHandler
+	return gate
+}
+
 // WithCustomTokenExtractor allows the specification of a custom function to extract a token from a request.
 // If a custom token extractor is not specified, the token will be extracted from the Authorization header.
 //
@@ -85,7 +238,8 @@ func (gate *Gate) WithCustomUnauthorizedResponseBody(unauthorizedResponseBody []
 // extracted by this custom token extractor.
 //
 // Note that for the sake of convenience, the token extracted from the request is passed the protected handlers request
-// context under the key TokenContextKey. This is especially useful if the token is in fact a session ID.
+// context, from which it can be retrieved using TokenFromContext. This is especially useful if the token is in fact a
+// session ID.
 func (gate *Gate) WithCustomTokenExtractor(customTokenExtractorFunc func(request *http.Request) string) *Gate {
 	gate.customTokenExtractorFunc = customTokenExtractorFunc
 	return gate
@@ -93,11 +247,275 @@ func (gate *Gate) WithCustomTokenExtractor(customTokenExtractorFunc func(request
 
 // WithRateLimit adds rate limiting to the Gate
 //
+// The rate limit is checked before authorization, so requests with a missing or invalid token count toward it too.
+//
 // If you just want to use a gate for rate limiting purposes:
 //
 //	gate := g8.New().WithRateLimit(50)
 func (gate *Gate) WithRateLimit(maximumRequestsPerSecond int) *Gate {
-	gate.rateLimiter = NewRateLimiter(maximumRequestsPerSecond)
+	return gate.WithRateLimiter(NewRateLimiter(maximumRequestsPerSecond))
+}
+
+// WithSlidingWindowRateLimit adds rate limiting to the Gate using a sliding window of an arbitrary duration.
+//
+// For instance, to allow at most 1000 requests over any 10 minutes period:
+//
+//	gate := g8.New().WithSlidingWindowRateLimit(1000, 10*time.Minute)
+//
+// See SlidingWindowRateLimiter for further documentation
+func (gate *Gate) WithSlidingWindowRateLimit(maximumRequests int, window time.Duration) *Gate {
+	return gate.WithRateLimiter(NewSlidingWindowRateLimiter(maximumRequests, window))
+}
+
+// WithRateLimiter adds rate limiting to the Gate using the Limiter passed as parameter.
+//
+// This is useful if you need a rate limiting algorithm other than the fixed window used by WithRateLimit.
+// For instance, to allow an average of 50 requests per second with bursts of up to 200 requests:
+//
+//	gate := g8.New().WithRateLimiter(g8.NewTokenBucketRateLimiter(50, 200))
+func (gate *Gate) WithRateLimiter(limiter Limiter) *Gate {
+	gate.rateLimiter = limiter
+	return gate
+}
+
+// WithRateLimitMaxWait makes the Gate wait for up to maxWait for the rate limit quota to become available instead of
+// rejecting requests that exceed the rate limit right away.
+//
+// This is useful to smooth out bursts of requests from clients such as batch jobs, which would rather have their
+// requests delayed than rejected. If the quota is still not available after maxWait, or if it is known that the quota
+// will not be available before maxWait, the request is rejected as usual. Waiting also stops if the request's context
+// is done (e.g. if the client disconnects).
+//
+// This applies to every rate limit enforced by the Gate. If a request goes through several rate limits, maxWait is
+// the total duration the request may wait for all of them, not the duration it may wait for each of them.
+//
+//	gate := g8.New().WithRateLimit(100).WithRateLimitMaxWait(500 * time.Millisecond)
+func (gate *Gate) WithRateLimitMaxWait(maxWait time.Duration) *Gate {
+	gate.rateLimitMaxWait = maxWait
+	return gate
+}
+
+// WithClientRateLimit adds rate limiting on a per-client basis to the Gate.
+//
+// Unlike WithRateLimit, which shares a single quota across every request going through the Gate, each authorized
+// client (identified by its token) gets its own quota of maximumRequestsPerSecond. As a result, a single client
+// exceeding its quota will not prevent other clients from going through.
+//
+// To keep memory usage bounded, at most DefaultRateLimiterPoolMaxSize rate limiters are kept in memory, and rate
+// limiters that haven't been used for DefaultRateLimiterPoolIdleTimeout are evicted.
+//
+// Note that this has no effect if the Gate has no authorization service, since there would be no client to rate
+// limit. This can be combined with WithRateLimit:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithClientRateLimit(10).WithRateLimit(1000)
+func (gate *Gate) WithClientRateLimit(maximumRequestsPerSecond int) *Gate {
+	return gate.WithClientRateLimitFunc(func(_ *Client) int {
+		return maximumRequestsPerSecond
+	})
+}
+
+// WithClientRateLimitFunc does the same thing as WithClientRateLimit, except that the maximum number of requests per
+// second is resolved for each client by the function passed as parameter, which allows different clients to have
+// different rate limits (e.g. based on Client.Permissions or Client.Data).
+//
+// If the function returns 0 or less for a client, said client will not be rate limited.
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithClientRateLimitFunc(func(client *g8.Client) int {
+//		if plan, _ := client.Data.(string); plan == "pro" {
+//			return 100
+//		}
+//		return 5
+//	})
+//
+// If a client's rate limit changes, its quota starts over with the new rate limit.
+func (gate *Gate) WithClientRateLimitFunc(clientRateLimitFunc func(client *Client) int) *Gate {
+	gate.clientRateLimiterPool = newRateLimiterPool(nil, DefaultRateLimiterPoolMaxSize, DefaultRateLimiterPoolIdleTimeout)
+	gate.clientRateLimitFunc = clientRateLimitFunc
+	return gate
+}
+
+// WithPermissionBasedClientRateLimit does the same thing as WithClientRateLimit, except that the maximum number of
+// requests per second of each client is determined by the client's permissions.
+//
+// If a client has more than one of the permissions in maximumRequestsPerSecondByPermission, the highest rate limit is
+// used. If a client has none of them, defaultMaximumRequestsPerSecond is used instead. A rate limit of 0 or less
+// means that the client will not be rate limited.
+//
+// For instance, to give clients with the permission "tier:pro" 100 requests per second and everybody else 5 requests
+// per second:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithPermissionBasedClientRateLimit(map[string]int{"tier:pro": 100}, 5)
+func (gate *Gate) WithPermissionBasedClientRateLimit(maximumRequestsPerSecondByPermission map[string]int, defaultMaximumRequestsPerSecond int) *Gate {
+	return gate.WithClientRateLimitFunc(func(client *Client) int {
+		maximumRequestsPerSecond, found := 0, false
+		for permission, permissionMaximumRequestsPerSecond := range maximumRequestsPerSecondByPermission {
+			if !client.HasPermission(permission) {
+				continue
+			}
+			if permissionMaximumRequestsPerSecond <= 0 {
+				// No rate limit is the highest rate limit there can be
+				return 0
+			}
+			if !found || permissionMaximumRequestsPerSecond > maximumRequestsPerSecond {
+				maximumRequestsPerSecond, found = permissionMaximumRequestsPerSecond, true
+			}
+		}
+		if !found {
+			return defaultMaximumRequestsPerSecond
+		}
+		return maximumRequestsPerSecond
+	})
+}
+
+// WithClientRateLimiter does the same thing as WithClientRateLimit, except that the function passed as parameter is
+// used to create the Limiter of each client. The key passed to said function is the client's token.
+//
+// For instance, to allow each client an average of 10 requests per second with bursts of up to 50 requests:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithClientRateLimiter(func(_ string) g8.Limiter {
+//		return g8.NewTokenBucketRateLimiter(10, 50)
+//	})
+//
+// Or, to share each client's quota across every instance of your application through a Store:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithClientRateLimiter(func(token string) g8.Limiter {
+//		return g8.NewStoreRateLimiter(yourRedisStore, "client:"+token, 10, time.Second)
+//	})
+func (gate *Gate) WithClientRateLimiter(newLimiterFunc func(key string) Limiter) *Gate {
+	gate.clientRateLimiterPool = newRateLimiterPool(newLimiterFunc, DefaultRateLimiterPoolMaxSize, DefaultRateLimiterPoolIdleTimeout)
+	gate.clientRateLimitFunc = nil
+	return gate
+}
+
+// WithIPRateLimit adds rate limiting on a per-IP basis to the Gate for requests that are not sent by an authorized
+// client, meaning requests going through a Gate that has no authorization service, as well as requests with a
+// missing or invalid token.
+//
+// Each IP address gets its own quota of maximumRequestsPerSecond, which makes it possible to throttle bots hammering
+// public endpoints without affecting every other user, as WithRateLimit would.
+//
+// The IP address is extracted using ExtractIPFromRequest. If the Gate sits behind one or more proxies, you must
+// specify them using WithTrustedProxies, otherwise every request will appear as if it came from the proxy.
+//
+// To keep memory usage bounded, at most DefaultRateLimiterPoolMaxSize rate limiters are kept in memory, and rate
+// limiters that haven't been used for DefaultRateLimiterPoolIdleTimeout are evicted.
+//
+//	gate := g8.New().WithIPRateLimit(10).WithTrustedProxies([]string{"10.0.0.0/8"})
+func (gate *Gate) WithIPRateLimit(maximumRequestsPerSecond int) *Gate {
+	return gate.WithIPRateLimiter(func(_ string) Limiter {
+		return NewRateLimiter(maximumRequestsPerSecond)
+	})
+}
+
+// WithIPRateLimiter does the same thing as WithIPRateLimit, except that the function passed as parameter is used to
+// create the Limiter of each IP address. The key passed to said function is the IP address.
+func (gate *Gate) WithIPRateLimiter(newLimiterFunc func(key string) Limiter) *Gate {
+	gate.ipRateLimiterPool = newRateLimiterPool(newLimiterFunc, DefaultRateLimiterPoolMaxSize, DefaultRateLimiterPoolIdleTimeout)
+	return gate
+}
+
+// WithConcurrencyLimit limits the number of requests that can be handled concurrently by the handlers protected by the
+// Gate. Requests received while maximumConcurrentRequests requests are already in flight are rejected, and a slot is
+// freed as soon as the protected handler returns.
+//
+// Unlike rate limiting, which bounds how many requests are accepted per unit of time, this bounds how many requests
+// are being processed at any given time, which is useful to protect long-running handlers from piling up.
+//
+// By default, rejected requests get a 429 Too Many Requests, but this can be changed using
+// WithConcurrencyLimitStatusCode.
+//
+//	gate := g8.New().WithConcurrencyLimit(20)
+//
+// Panics if maximumConcurrentRequests is not positive, since every request would be rejected.
+func (gate *Gate) WithConcurrencyLimit(maximumConcurrentRequests int) *Gate {
+	gate.concurrencyLimiter = newConcurrencyLimiter(maximumConcurrentRequests)
+	return gate
+}
+
+// WithClientConcurrencyLimit does the same thing as WithConcurrencyLimit, except that each authorized client
+// (identified by its token) is limited to maximumConcurrentRequests requests in flight.
+//
+// Note that this has no effect if the Gate has no authorization service, since there would be no client to limit.
+// This can be combined with WithConcurrencyLimit:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithClientConcurrencyLimit(2).WithConcurrencyLimit(50)
+//
+// Like WithConcurrencyLimit, panics if maximumConcurrentRequests is not positive.
+func (gate *Gate) WithClientConcurrencyLimit(maximumConcurrentRequests int) *Gate {
+	gate.clientConcurrencyLimiter = newConcurrencyLimiter(maximumConcurrentRequests)
+	return gate
+}
+
+// WithConcurrencyLimitStatusCode sets the status code returned when a request is rejected because of
+// WithConcurrencyLimit or WithClientConcurrencyLimit.
+//
+// Defaults to http.StatusTooManyRequests (429), but you may prefer http.StatusServiceUnavailable (503) if the limit is
+// meant to protect your service's capacity rather than to enforce a quota on your clients.
+func (gate *Gate) WithConcurrencyLimitStatusCode(statusCode int) *Gate {
+	gate.tooManyConcurrentRequestsStatusCode = statusCode
+	return gate
+}
+
+// WithQuota adds a Quota to the Gate, limiting the number of requests each client can make over a calendar period.
+//
+// Calling this function multiple times will add multiple quotas, in which case a request is only accepted if none of
+// the quotas have been exhausted. For instance, to allow 1000 requests per day and 20000 requests per month:
+//
+//	gate := g8.New().
+//		WithAuthorizationService(authorizationService).
+//		WithQuota(g8.NewQuota(1000, g8.QuotaPeriodDaily)).
+//		WithQuota(g8.NewQuota(20000, g8.QuotaPeriodMonthly))
+//
+// Only requests that go through to the protected handler count toward the quota, and requests rejected because a
+// quota has been exhausted get a 429 Too Many Requests with the body set through WithCustomQuotaExceededResponseBody.
+//
+// Note that this has no effect if the Gate has no authorization service, since there would be no client to count the
+// usage of. See Quota.Usage to retrieve the usage of a client.
+//
+// Panics if the Gate already has a quota with the same period and key prefix, since both quotas would count every
+// request in the same counters if they shared a Store. See Quota.WithKeyPrefix.
+func (gate *Gate) WithQuota(quota *Quota) *Gate {
+	for _, existingQuota := range gate.quotas {
+		if existingQuota.period == quota.period && existingQuota.keyPrefix == quota.keyPrefix {
+			panic("g8: gate already has a " + quota.period.String() + " quota with the key prefix " + quota.keyPrefix)
+		}
+	}
+	gate.quotas = append(gate.quotas, quota)
+	return gate
+}
+
+// WithTrustedProxies specifies the proxies, as a slice of CIDRs (e.g. 10.0.0.0/8) or IP addresses (e.g. 10.0.0.1),
+// that are trusted to disclose the address of the client through the header set with WithTrustedProxyHeader, which
+// is X-Forwarded-For by default.
+//
+// If a request does not come from a trusted proxy, that header is ignored, since it could've been set by the client
+// itself.
+//
+// Panics if one of the trusted proxies is neither a valid CIDR nor a valid IP address.
+func (gate *Gate) WithTrustedProxies(trustedProxies []string) *Gate {
+	prefixes, err := parseTrustedProxies(trustedProxies)
+	if err != nil {
+		panic("g8: invalid trusted proxy: " + err.Error())
+	}
+	gate.trustedProxies = prefixes
+	return gate
+}
+
+// WithTrustedProxyHeader sets the header through which the trusted proxies (see WithTrustedProxies) disclose the
+// address of the client. Defaults to XForwardedForHeader.
+//
+// Only that header is read, so it must be one that your proxies always write: if a proxy only appends to
+// X-Forwarded-For, a Forwarded header sent by the client would reach the Gate unchanged. Headers other than
+// ForwardedHeader (e.g. X-Real-IP) are expected to be a comma-separated list of addresses, like X-Forwarded-For.
+//
+//	gate := g8.New().WithTrustedProxies([]string{"10.0.0.0/8"}).WithTrustedProxyHeader(g8.ForwardedHeader)
+//
+// Panics if header is empty.
+func (gate *Gate) WithTrustedProxyHeader(header string) *Gate {
+	if len(header) == 0 {
+		panic("g8: trusted proxy header must not be empty")
+	}
+	gate.trustedProxyHeader = header
 	return gate
 }
 
@@ -114,9 +532,13 @@ func (gate *Gate) WithRateLimit(maximumRequestsPerSecond int) *Gate {
 //	// With protection
 //	router.Handle("/handle", gate.Protect(yourHandler))
 //
-// The token extracted from the request is passed to the handlerFunc request context under the key TokenContextKey
-func (gate *Gate) Protect(handler http.Handler) http.Handler {
-	return gate.ProtectWithPermissions(handler, nil)
+// The token extracted from the request and the client it belongs to are passed to the handlerFunc request context,
+// from which they can be retrieved using TokenFromContext and ClientFromContext.
+//
+// Options may be passed to customize how this specific handler is protected (e.g. WithRouteRateLimit or WithCost).
+// If you need a function with the signature of a middleware, use PermissionMiddleware without any permission instead.
+func (gate *Gate) Protect(handler http.Handler, options ...ProtectOption) http.Handler {
+	return gate.ProtectWithPermissions(handler, nil, options...)
 }
 
 // ProtectWithPermissions secures a handler, requiring requests going through to have a valid Authorization Bearer token
@@ -131,21 +553,31 @@ func (gate *Gate) Protect(handler http.Handler) http.Handler {
 //	// With protection
 //	router.Handle("/handle", gate.ProtectWithPermissions(yourHandler, []string{"admin"}))
 //
-// The token extracted from the request is passed to the handlerFunc request context under the key TokenContextKey
-func (gate *Gate) ProtectWithPermissions(handler http.Handler, permissions []string) http.Handler {
+// The token extracted from the request and the client it belongs to are passed to the handlerFunc request context,
+// from which they can be retrieved using TokenFromContext and ClientFromContext.
+//
+// Permissions are interpreted literally, unless WithPermissionTemplates is passed, in which case the names of path
+// values between braces are expanded using the path values of each request (see http.Request.PathValue) before
+// checking whether the client has them. For instance, the following requires the permission "org:acme:write" for a
+// request to /orgs/acme/projects:
+//
+//	router.Handle("POST /orgs/{org}/projects", gate.ProtectWithPermissions(createProjectHandler, []string{"org:{org}:write"}, g8.WithPermissionTemplates()))
+//
+// Options may be passed to customize how this specific handler is protected (e.g. WithRouteRateLimit or WithCost).
+func (gate *Gate) ProtectWithPermissions(handler http.Handler, permissions []string, options ...ProtectOption) http.Handler {
 	return gate.ProtectFuncWithPermissions(func(writer http.ResponseWriter, request *http.Request) {
 		handler.ServeHTTP(writer, request)
-	}, permissions)
+	}, permissions, options...)
 }
 
 // ProtectWithPermission does the same thing as ProtectWithPermissions, but for a single permission instead of a
 // slice of permissions
 //
 // See ProtectWithPermissions for further documentation
-func (gate *Gate) ProtectWithPermission(handler http.Handler, permission string) http.Handler {
+func (gate *Gate) ProtectWithPermission(handler http.Handler, permission string, options ...ProtectOption) http.Handler {
 	return gate.ProtectFuncWithPermissions(func(writer http.ResponseWriter, request *http.Request) {
 		handler.ServeHTTP(writer, request)
-	}, []string{permission})
+	}, []string{permission}, options...)
 }
 
 // ProtectFunc secures a handlerFunc, requiring requests going through to have a valid Authorization Bearer token.
@@ -161,9 +593,40 @@ func (gate *Gate) ProtectWithPermission(handler http.Handler, permission string)
 //	// With protection
 //	router.HandleFunc("/handle", gate.ProtectFunc(yourHandlerFunc))
 //
-// The token extracted from the request is passed to the handlerFunc request context under the key TokenContextKey
-func (gate *Gate) ProtectFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
-	return gate.ProtectFuncWithPermissions(handlerFunc, nil)
+// The token extracted from the request and the client it belongs to are passed to the handlerFunc request context,
+// from which they can be retrieved using TokenFromContext and ClientFromContext.
+//
+// Options may be passed to customize how this specific handlerFunc is protected (e.g. WithRouteRateLimit or WithCost).
+func (gate *Gate) ProtectFunc(handlerFunc http.HandlerFunc, options ...ProtectOption) http.HandlerFunc {
+	return gate.ProtectFuncWithPermissions(handlerFunc, nil, options...)
+}
+
+// ProtectWithAnyPermission secures a handler, requiring requests going through to have a valid Authorization Bearer
+// token as well as at least one of the permissions passed as parameter.
+//
+// For instance, to allow clients that have either the permission "admin" or the permission "support":
+//
+//	router.Handle("/tickets", gate.ProtectWithAnyPermission(ticketsHandler, []string{"admin", "support"}))
+//
+// See ProtectWithPermissions for further documentation
+func (gate *Gate) ProtectWithAnyPermission(handler http.Handler, permissions []string, options ...ProtectOption) http.Handler {
+	return gate.ProtectWithRequirement(handler, AnyPermission(permissions...), options...)
+}
+
+// ProtectWithRequirement secures a handler, requiring requests going through to have a valid Authorization Bearer token
+// as well as to satisfy a Requirement, which may combine permissions using AllOf, AnyOf and Not.
+//
+// For instance, to allow clients that have the permission "admin", or the permission "support" but not the
+// permission "intern":
+//
+//	requirement := g8.AnyOf(g8.Permission("admin"), g8.AllOf(g8.Permission("support"), g8.Not(g8.Permission("intern"))))
+//	router.Handle("/tickets", gate.ProtectWithRequirement(ticketsHandler, requirement))
+//
+// See ProtectWithPermissions for further documentation
+func (gate *Gate) ProtectWithRequirement(handler http.Handler, requirement Requirement, options ...ProtectOption) http.Handler {
+	return gate.ProtectFuncWithRequirement(func(writer http.ResponseWriter, request *http.Request) {
+		handler.ServeHTTP(writer, request)
+	}, requirement, options...)
 }
 
 // ProtectFuncWithPermissions secures a handler, requiring requests going through to have a valid Authorization Bearer
@@ -178,39 +641,328 @@ func (gate *Gate) ProtectFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
 //	// With protection
 //	router.HandleFunc("/handle", gate.ProtectFuncWithPermissions(yourHandlerFunc, []string{"admin"}))
 //
-// The token extracted from the request is passed to the handlerFunc request context under the key TokenContextKey
-func (gate *Gate) ProtectFuncWithPermissions(handlerFunc http.HandlerFunc, permissions []string) http.HandlerFunc {
+// The token extracted from the request and the client it belongs to are passed to the handlerFunc request context,
+// from which they can be retrieved using TokenFromContext and ClientFromContext.
+//
+// Options may be passed to customize how this specific handler is protected (e.g. WithRouteRateLimit or WithCost).
+func (gate *Gate) ProtectFuncWithPermissions(handlerFunc http.HandlerFunc, permissions []string, options ...ProtectOption) http.HandlerFunc {
+	return gate.ProtectFuncWithRequirement(handlerFunc, AllPermissions(permissions...), options...)
+}
+
+// ProtectFuncWithAnyPermission does the same thing as ProtectWithAnyPermission, but for a handlerFunc
+//
+// See ProtectWithAnyPermission for further documentation
+func (gate *Gate) ProtectFuncWithAnyPermission(handlerFunc http.HandlerFunc, permissions []string, options ...ProtectOption) http.HandlerFunc {
+	return gate.ProtectFuncWithRequirement(handlerFunc, AnyPermission(permissions...), options...)
+}
+
+// ProtectFuncWithRequirement does the same thing as ProtectWithRequirement, but for a handlerFunc
+//
+// See ProtectWithRequirement for further documentation
+func (gate *Gate) ProtectFuncWithRequirement(handlerFunc http.HandlerFunc, requirement Requirement, options ...ProtectOption) http.HandlerFunc {
+	protectOptions := newProtectOptions(options)
+	var hasPermissionTemplates bool
+	if protectOptions.permissionTemplates {
+		var err error
+		if requirement, hasPermissionTemplates, err = compilePermissionTemplates(requirement); err != nil {
+			panic("g8: " + err.Error())
+		}
+	}
 	return func(writer http.ResponseWriter, request *http.Request) {
-		if gate.rateLimiter != nil {
-			if !gate.rateLimiter.Try() {
-				writer.WriteHeader(http.StatusTooManyRequests)
-				_, _ = writer.Write(gate.tooManyRequestsResponseBody)
-				return
-			}
+		var rateLimitTracker rateLimitTracker
+		var token string
+		cost := protectOptions.cost(request)
+		// The gate-wide rate limit is checked before authorization so that it also throttles requests with a missing
+		// or invalid token, which would otherwise be free to brute force tokens or flood the client provider
+		if gate.rateLimiter != nil && !gate.takeRateLimit(request, &rateLimitTracker, gate.rateLimiter, cost) {
+			gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+			return
 		}
 		if gate.authorizationService != nil {
-			token := gate.ExtractTokenFromRequest(request)
-			if client, authorized := gate.authorizationService.Authorize(token, permissions); !authorized {
-				writer.WriteHeader(http.StatusUnauthorized)
-				_, _ = writer.Write(gate.unauthorizedResponseBody)
+			token = gate.ExtractTokenFromRequest(request)
+			requestRequirement := requirement
+			if hasPermissionTemplates {
+				var expanded bool
+				if requestRequirement, expanded = expandPermissionTemplates(requirement, request); !expanded {
+					// A requirement that cannot be satisfied, so that the request goes through the same path as any
+					// other request that is not authorized
+					requestRequirement = AnyOf()
+				}
+			}
+			if client, err := gate.authorizationService.AuthorizeWithReason(token, requestRequirement); err != nil {
+				if gate.ipRateLimiterPool != nil && !gate.takeRateLimit(request, &rateLimitTracker, gate.ipRateLimiterPool.get(gate.ExtractIPFromRequest(request)), cost) {
+					gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+					return
+				}
+				gate.rejectUnauthorized(writer, request, &rateLimitTracker, err, requestRequirement)
 				return
 			} else {
-				request = request.WithContext(context.WithValue(request.Context(), TokenContextKey, token))
-				if client != nil && client.Data != nil {
-					request = request.WithContext(context.WithValue(request.Context(), DataContextKey, client.Data))
+				if gate.clientRateLimiterPool != nil && !gate.takeClientRateLimit(request, token, client, cost, &rateLimitTracker) {
+					gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+					return
 				}
+				request = request.WithContext(newContextWithClient(request.Context(), token, client))
 			}
+		} else if gate.ipRateLimiterPool != nil && !gate.takeRateLimit(request, &rateLimitTracker, gate.ipRateLimiterPool.get(gate.ExtractIPFromRequest(request)), cost) {
+			gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+			return
 		}
+		if protectOptions.rateLimiter != nil && !gate.takeRateLimit(request, &rateLimitTracker, protectOptions.rateLimiter, cost) {
+			gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+			return
+		}
+		if gate.clientConcurrencyLimiter != nil && gate.authorizationService != nil {
+			if !gate.clientConcurrencyLimiter.acquire(token) {
+				gate.rejectTooManyConcurrentRequests(writer, request, &rateLimitTracker)
+				return
+			}
+			defer gate.clientConcurrencyLimiter.release(token)
+		}
+		if gate.concurrencyLimiter != nil {
+			if !gate.concurrencyLimiter.acquire("") {
+				gate.rejectTooManyConcurrentRequests(writer, request, &rateLimitTracker)
+				return
+			}
+			defer gate.concurrencyLimiter.release("")
+		}
+		if len(gate.quotas) > 0 && gate.authorizationService != nil {
+			if exhaustedQuotaUsage, ok := gate.consumeQuotas(token); !ok {
+				gate.rejectQuotaExceeded(writer, request, &rateLimitTracker, exhaustedQuotaUsage)
+				return
+			}
+		}
+		rateLimitTracker.writeHeaders(writer)
 		handlerFunc(writer, request)
 	}
 }
 
+// rejectUnauthorized responds to a request that was not authorized for the given reason.
+//
+// If the client was identified but does not have the required permissions, the request is rejected with a
+// 403 Forbidden. Otherwise, it is rejected with a 401 Unauthorized. In both cases, a WWW-Authenticate header describing
+// the reason (see newBearerChallenge) is added to the response.
+func (gate *Gate) rejectUnauthorized(writer http.ResponseWriter, request *http.Request, rateLimitTracker *rateLimitTracker, reason error, requirement Requirement) {
+	rateLimitTracker.writeHeaders(writer)
+	writer.Header().Set(WWWAuthenticateHeader, newBearerChallenge(gate.realm, reason, requirement))
+	gate.reject(writer, request, reason)
+}
+
+// rejectTooManyConcurrentRequests responds to a request that exceeded the concurrency limit
+func (gate *Gate) rejectTooManyConcurrentRequests(writer http.ResponseWriter, request *http.Request, rateLimitTracker *rateLimitTracker) {
+	rateLimitTracker.refund()
+	rateLimitTracker.writeHeaders(writer)
+	gate.reject(writer, request, ErrTooManyConcurrentRequests)
+}
+
+// consumeQuotas consumes every quota of the client with the given token and returns whether the attempt was
+// successful. If one of the quotas has been exhausted, the quotas consumed prior to it are refunded, and the usage of
+// the exhausted quota is returned.
+func (gate *Gate) consumeQuotas(token string) (QuotaUsage, bool) {
+	// Every quota is consumed and refunded for the same time, so that a refund is given back to the period the request
+	// was counted in, even if a new period has started in the meantime
+	now := time.Now()
+	for i, quota := range gate.quotas {
+		if usage, ok := quota.consume(token, now); !ok {
+			for _, consumedQuota := range gate.quotas[:i] {
+				consumedQuota.refund(token, now)
+			}
+			return usage, false
+		}
+	}
+	return QuotaUsage{}, true
+}
+
+// rejectQuotaExceeded responds to a request sent by a client that has exhausted its quota
+func (gate *Gate) rejectQuotaExceeded(writer http.ResponseWriter, request *http.Request, rateLimitTracker *rateLimitTracker, exhaustedQuotaUsage QuotaUsage) {
+	rateLimitTracker.refund()
+	rateLimitTracker.writeHeaders(writer)
+	writer.Header().Set(RetryAfterHeader, strconv.Itoa(max(1, durationToSeconds(time.Until(exhaustedQuotaUsage.ResetAt)))))
+	gate.reject(writer, request, ErrQuotaExceeded)
+}
+
+// takeClientRateLimit consumes cost units of the quota of the client's rate limiter and returns whether the attempt
+// was successful
+func (gate *Gate) takeClientRateLimit(request *http.Request, token string, client *Client, cost int, rateLimitTracker *rateLimitTracker) bool {
+	if gate.clientRateLimitFunc == nil {
+		return gate.takeRateLimit(request, rateLimitTracker, gate.clientRateLimiterPool.get(token), cost)
+	}
+	maximumRequestsPerSecond := gate.clientRateLimitFunc(client)
+	if maximumRequestsPerSecond <= 0 {
+		return true
+	}
+	// The rate limit is part of the key so that if a client's rate limit changes, a new rate limiter is created
+	key := strconv.Itoa(maximumRequestsPerSecond) + ":" + token
+	limiter := gate.clientRateLimiterPool.getOrCreate(key, func() Limiter {
+		return NewRateLimiter(maximumRequestsPerSecond)
+	})
+	return gate.takeRateLimit(request, rateLimitTracker, limiter, cost)
+}
+
+// takeRateLimit consumes cost units of the quota of a Limiter, records the outcome of the attempt in the
+// rateLimitTracker and returns whether the attempt was successful.
+//
+// If a maximum wait was configured through WithRateLimitMaxWait, it waits for the quota to be available until said
+// duration has elapsed since the request first waited for a Limiter, or until the request's context is done.
+func (gate *Gate) takeRateLimit(request *http.Request, rateLimitTracker *rateLimitTracker, limiter Limiter, cost int) bool {
+	var result RateLimitResult
+	if gate.rateLimitMaxWait <= 0 {
+		result = limiter.TakeN(cost)
+	} else {
+		if rateLimitTracker.waitDeadline.IsZero() {
+			// Every Limiter shares the same deadline, so that a request going through several of them doesn't wait
+			// for longer than the maximum wait in total
+			rateLimitTracker.waitDeadline = time.Now().Add(gate.rateLimitMaxWait)
+		}
+		ctx, cancel := context.WithDeadline(request.Context(), rateLimitTracker.waitDeadline)
+		result, _ = waitForLimiter(ctx, limiter, cost)
+		cancel()
+	}
+	if result.Allowed {
+		rateLimitTracker.consume(limiter, result, cost)
+	}
+	return rateLimitTracker.track(result)
+}
+
+// rejectTooManyRequests responds to a request that exceeded the rate limit quota
+func (gate *Gate) rejectTooManyRequests(writer http.ResponseWriter, request *http.Request, rateLimitTracker *rateLimitTracker) {
+	rateLimitTracker.refund()
+	rateLimitTracker.writeHeaders(writer)
+	gate.reject(writer, request, ErrTooManyRequests)
+}
+
+// reject writes the response to a request rejected for the given reason, either through the error handler if one was
+// set, or by writing the status code and the response body associated with the reason
+func (gate *Gate) reject(writer http.ResponseWriter, request *http.Request, reason error) {
+	if gate.errorHandler != nil {
+		gate.errorHandler(writer, request, reason)
+		return
+	}
+	writer.WriteHeader(gate.statusCode(reason))
+	_, _ = writer.Write(gate.responseBody(reason))
+}
+
+// statusCode returns the status code of the response to a request rejected for the given reason
+func (gate *Gate) statusCode(reason error) int {
+	switch {
+	case errors.Is(reason, ErrInsufficientPermissions):
+		return http.StatusForbidden
+	case errors.Is(reason, ErrTooManyRequests), errors.Is(reason, ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(reason, ErrTooManyConcurrentRequests):
+		return gate.tooManyConcurrentRequestsStatusCode
+	case errors.Is(reason, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+// responseBody returns the body of the response to a request rejected for the given reason
+func (gate *Gate) responseBody(reason error) []byte {
+	switch {
+	case errors.Is(reason, ErrInsufficientPermissions):
+		return gate.forbiddenResponseBody
+	case errors.Is(reason, ErrTooManyRequests):
+		return gate.tooManyRequestsResponseBody
+	case errors.Is(reason, ErrTooManyConcurrentRequests):
+		return gate.tooManyConcurrentRequestsResponseBody
+	case errors.Is(reason, ErrQuotaExceeded):
+		return gate.quotaExceededResponseBody
+	case errors.Is(reason, ErrMethodNotAllowed):
+		return gate.methodNotAllowedResponseBody
+	default:
+		return gate.unauthorizedResponseBody
+	}
+}
+
 // ProtectFuncWithPermission does the same thing as ProtectFuncWithPermissions, but for a single permission instead of a
 // slice of permissions
 //
 // See ProtectFuncWithPermissions for further documentation
-func (gate *Gate) ProtectFuncWithPermission(handlerFunc http.HandlerFunc, permission string) http.HandlerFunc {
-	return gate.ProtectFuncWithPermissions(handlerFunc, []string{permission})
+func (gate *Gate) ProtectFuncWithPermission(handlerFunc http.HandlerFunc, permission string, options ...ProtectOption) http.HandlerFunc {
+	return gate.ProtectFuncWithPermissions(handlerFunc, []string{permission}, options...)
+}
+
+// ProtectWithMethodPermissions secures a handler, requiring requests going through to have a valid Authorization
+// Bearer token as well as the permissions associated with the request's method in permissionsByMethod.
+//
+// Requests whose method is not in permissionsByMethod are rejected with a 405 Method Not Allowed, along with an Allow
+// header listing the methods that are. Like any other request, they go through the gate-wide and per-IP rate limits
+// first, and the response is written through the error handler, if any, with ErrMethodNotAllowed as reason. The
+// response body can be changed using WithCustomMethodNotAllowedResponseBody.
+//
+// If GET is in permissionsByMethod but HEAD is not, HEAD requests require the same permissions as GET requests, like
+// http.ServeMux does for its patterns.
+//
+// For instance, to require "items:read" to list items and "items:write" to create or delete them:
+//
+//	router.Handle("/items", gate.ProtectWithMethodPermissions(itemsHandler, map[string][]string{
+//		http.MethodGet:    {"items:read"},
+//		http.MethodPost:   {"items:write"},
+//		http.MethodDelete: {"items:write"},
+//	}))
+//
+// The options passed apply to every method, and since they're shared, so is the quota of a route rate limit.
+// Methods are case-insensitive, and specifying the same method more than once (e.g. "get" and "GET") panics.
+//
+// See ProtectWithPermissions for further documentation
+func (gate *Gate) ProtectWithMethodPermissions(handler http.Handler, permissionsByMethod map[string][]string, options ...ProtectOption) http.Handler {
+	return gate.ProtectFuncWithMethodPermissions(func(writer http.ResponseWriter, request *http.Request) {
+		handler.ServeHTTP(writer, request)
+	}, permissionsByMethod, options...)
+}
+
+// ProtectFuncWithMethodPermissions does the same thing as ProtectWithMethodPermissions, but for a handlerFunc
+//
+// See ProtectWithMethodPermissions for further documentation
+func (gate *Gate) ProtectFuncWithMethodPermissions(handlerFunc http.HandlerFunc, permissionsByMethod map[string][]string, options ...ProtectOption) http.HandlerFunc {
+	handlerFuncByMethod := make(map[string]http.HandlerFunc, len(permissionsByMethod)+1)
+	allowedMethods := make([]string, 0, len(permissionsByMethod)+1)
+	for method, permissions := range permissionsByMethod {
+		method = strings.ToUpper(method)
+		if _, exists := handlerFuncByMethod[method]; exists {
+			panic("g8: method " + method + " is specified more than once")
+		}
+		handlerFuncByMethod[method] = gate.ProtectFuncWithPermissions(handlerFunc, permissions, options...)
+		allowedMethods = append(allowedMethods, method)
+	}
+	if _, hasHead := handlerFuncByMethod[http.MethodHead]; !hasHead {
+		if protectedHandlerFunc, hasGet := handlerFuncByMethod[http.MethodGet]; hasGet {
+			handlerFuncByMethod[http.MethodHead] = protectedHandlerFunc
+			allowedMethods = append(allowedMethods, http.MethodHead)
+		}
+	}
+	slices.Sort(allowedMethods)
+	allowHeaderValue := strings.Join(allowedMethods, ", ")
+	protectOptions := newProtectOptions(options)
+	return func(writer http.ResponseWriter, request *http.Request) {
+		protectedHandlerFunc, exists := handlerFuncByMethod[request.Method]
+		if !exists {
+			gate.rejectMethodNotAllowed(writer, request, allowHeaderValue, protectOptions.cost(request))
+			return
+		}
+		protectedHandlerFunc(writer, request)
+	}
+}
+
+// rejectMethodNotAllowed responds to a request whose method is not allowed by a handler protected with
+// ProtectWithMethodPermissions.
+//
+// Since the request is rejected without being authorized, the gate-wide and per-IP rate limits are checked first, like
+// they are for requests with a missing or invalid token.
+func (gate *Gate) rejectMethodNotAllowed(writer http.ResponseWriter, request *http.Request, allowHeaderValue string, cost int) {
+	var rateLimitTracker rateLimitTracker
+	if gate.rateLimiter != nil && !gate.takeRateLimit(request, &rateLimitTracker, gate.rateLimiter, cost) {
+		gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+		return
+	}
+	if gate.ipRateLimiterPool != nil && !gate.takeRateLimit(request, &rateLimitTracker, gate.ipRateLimiterPool.get(gate.ExtractIPFromRequest(request)), cost) {
+		gate.rejectTooManyRequests(writer, request, &rateLimitTracker)
+		return
+	}
+	rateLimitTracker.writeHeaders(writer)
+	writer.Header().Set("Allow", allowHeaderValue)
+	gate.reject(writer, request, ErrMethodNotAllowed)
 }
 
 // ExtractTokenFromRequest extracts a token from a request.
@@ -237,9 +989,86 @@ func (gate *Gate) ExtractTokenFromRequest(request *http.Request) string {
 //	router.Use(gate.PermissionMiddleware("admin"))
 //	router.Handle("/admin/handle", adminHandler)
 //
-// If you do not want to protect a router with a specific permission, you can use Gate.Protect instead.
+// If you do not want to protect a router with a specific permission, you can call PermissionMiddleware without any
+// permission.
 func (gate *Gate) PermissionMiddleware(permissions ...string) func(http.Handler) http.Handler {
+	return gate.PermissionMiddlewareWithOptions(permissions)
+}
+
+// PermissionMiddlewareWithOptions does the same thing as PermissionMiddleware, but also accepts options to customize
+// how the handlers are protected.
+//
+// Note that the options are shared by every handler wrapped by the middleware, so for instance, passing
+// WithRouteRateLimit will make every handler wrapped by the middleware share the same quota:
+//
+//	router := mux.NewRouter()
+//	router.Use(gate.PermissionMiddlewareWithOptions([]string{"export"}, g8.WithRouteRateLimit(1)))
+//	router.Handle("/export/users", exportUsersHandler)
+func (gate *Gate) PermissionMiddlewareWithOptions(permissions []string, options ...ProtectOption) func(http.Handler) http.Handler {
+	return gate.RequirementMiddleware(AllPermissions(permissions...), options...)
+}
+
+// AnyPermissionMiddleware is a middleware that behaves like ProtectWithAnyPermission, meaning that clients must have
+// at least one of the permissions passed as parameter.
+//
+//	router := mux.NewRouter()
+//	router.Use(gate.AnyPermissionMiddleware("admin", "support"))
+//	router.Handle("/tickets", ticketsHandler)
+//
+// See PermissionMiddleware for further documentation
+func (gate *Gate) AnyPermissionMiddleware(permissions ...string) func(http.Handler) http.Handler {
+	return gate.RequirementMiddleware(AnyPermission(permissions...))
+}
+
+// AnyPermissionMiddlewareWithOptions does the same thing as AnyPermissionMiddleware, but also accepts options to
+// customize how the handlers are protected.
+//
+// Like PermissionMiddlewareWithOptions, the options passed are shared by every handler wrapped by the middleware.
+//
+// See PermissionMiddleware for further documentation
+func (gate *Gate) AnyPermissionMiddlewareWithOptions(permissions []string, options ...ProtectOption) func(http.Handler) http.Handler {
+	return gate.RequirementMiddleware(AnyPermission(permissions...), options...)
+}
+
+// RequirementMiddleware is a middleware that behaves like ProtectWithRequirement.
+//
+// Like PermissionMiddlewareWithOptions, the options passed are shared by every handler wrapped by the middleware.
+//
+// See PermissionMiddleware for further documentation
+func (gate *Gate) RequirementMiddleware(requirement Requirement, options ...ProtectOption) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return gate.ProtectWithPermissions(next, permissions)
+		return gate.ProtectWithRequirement(next, requirement, options...)
 	}
 }
+
+// ProtectWithExpression secures a handler, requiring requests going through to have a valid Authorization Bearer token
+// as well as to satisfy a boolean permission expression (see ParseRequirement for the syntax).
+//
+// The expression is parsed once, when ProtectWithExpression is called, and it panics if the expression is invalid so
+// that mistakes are caught when your application starts rather than when requests are received:
+//
+//	router.Handle("/invoices", gate.ProtectWithExpression(invoicesHandler, "(admin || (billing && read)) && !suspended"))
+//
+// See ProtectWithRequirement for further documentation
+func (gate *Gate) ProtectWithExpression(handler http.Handler, expression string, options ...ProtectOption) http.Handler {
+	return gate.ProtectWithRequirement(handler, MustParseRequirement(expression), options...)
+}
+
+// ProtectFuncWithExpression does the same thing as ProtectWithExpression, but for a handlerFunc
+//
+// See ProtectWithExpression for further documentation
+func (gate *Gate) ProtectFuncWithExpression(handlerFunc http.HandlerFunc, expression string, options ...ProtectOption) http.HandlerFunc {
+	return gate.ProtectFuncWithRequirement(handlerFunc, MustParseRequirement(expression), options...)
+}
+
+// ExpressionMiddleware is a middleware that behaves like ProtectWithExpression.
+//
+// Like ProtectWithExpression, it panics if the expression is invalid.
+//
+//	router := mux.NewRouter()
+//	router.Use(gate.ExpressionMiddleware("admin || support"))
+//
+// See PermissionMiddleware for further documentation
+func (gate *Gate) ExpressionMiddleware(expression string, options ...ProtectOption) func(http.Handler) http.Handler {
+	return gate.RequirementMiddleware(MustParseRequirement(expression), options...)
+}
@@ -107,8 +107,8 @@ func BenchmarkGate_ProtectWithPermissionsAndValidTokenButInsufficientPermissions
 	for n := 0; n < b.N; n++ {
 		responseRecorder := httptest.NewRecorder()
 		router.ServeHTTP(responseRecorder, request)
-		if responseRecorder.Code != http.StatusUnauthorized {
-			b.Fatalf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusUnauthorized, responseRecorder.Code)
+		if responseRecorder.Code != http.StatusForbidden {
+			b.Fatalf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusForbidden, responseRecorder.Code)
 		}
 	}
 	b.ReportAllocs()
@@ -1,6 +1,8 @@
 package g8
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -208,9 +210,9 @@ func TestGate_ProtectWithPermissionsWhenValidTokenAndInsufficientPermissionsWhil
 
 	// Since the client returned from the mockClientProvider has FirstTestProviderClientPermission and
 	// SecondTestProviderClientPermission and the testHandler is protected by a permission that the client does not
-	// have, the request should be not be authorized
-	if responseRecorder.Code != http.StatusUnauthorized {
-		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusUnauthorized, responseRecorder.Code)
+	// have, the request should be forbidden
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusForbidden, responseRecorder.Code)
 	}
 }
 
@@ -242,9 +244,9 @@ func TestGate_ProtectWithPermissionsWhenClientHasInsufficientPermissions(t *test
 	router.ServeHTTP(responseRecorder, request)
 
 	// Since the client registered directly in the AuthorizationService has the permission "mod" and the
-	// testHandler is protected by the permission "admin", the request should be not be authorized
-	if responseRecorder.Code != http.StatusUnauthorized {
-		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusUnauthorized, responseRecorder.Code)
+	// testHandler is protected by the permission "admin", the request should be forbidden
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusForbidden, responseRecorder.Code)
 	}
 }
 
@@ -276,7 +278,257 @@ func TestGate_ProtectWithPermissions(t *testing.T) {
 	checkRouterOutput(t, router, "/update", http.StatusOK)
 	checkRouterOutput(t, router, "/delete", http.StatusOK)
 	checkRouterOutput(t, router, "/crud", http.StatusOK)
-	checkRouterOutput(t, router, "/backup", http.StatusUnauthorized)
+	checkRouterOutput(t, router, "/backup", http.StatusForbidden)
+}
+
+func TestGate_ProtectWithAnyPermission(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("mytoken").WithPermissions([]string{"support", "intern"})))
+
+	router := http.NewServeMux()
+	router.Handle("/tickets", gate.ProtectWithAnyPermission(&testHandler{}, []string{"admin", "support"}))
+	router.Handle("/billing", gate.ProtectWithAnyPermission(&testHandler{}, []string{"admin", "billing"}))
+	router.HandleFunc("/tickets-func", gate.ProtectFuncWithAnyPermission(testHandlerFunc, []string{"admin", "support"}))
+	router.Handle("/tickets-middleware", gate.AnyPermissionMiddleware("admin", "support")(&testHandler{}))
+	router.Handle("/billing-middleware", gate.AnyPermissionMiddleware("admin", "billing")(&testHandler{}))
+	router.Handle("/refunds", gate.ProtectWithRequirement(&testHandler{}, AnyOf(Permission("admin"), AllOf(Permission("support"), Not(Permission("intern"))))))
+	router.HandleFunc("/escalations", gate.ProtectFuncWithRequirement(testHandlerFunc, AllOf(Permission("support"), Permission("intern"))))
+	router.Handle("/internal", gate.RequirementMiddleware(Not(Permission("intern")))(&testHandler{}))
+
+	checkRouterOutput := func(t *testing.T, router *http.ServeMux, url string, expectedResponseCode int) {
+		t.Run(strings.TrimPrefix(url, "/"), func(t *testing.T) {
+			request, _ := http.NewRequest("GET", url, http.NoBody)
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "mytoken"))
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+			}
+		})
+	}
+
+	checkRouterOutput(t, router, "/tickets", http.StatusOK)
+	checkRouterOutput(t, router, "/billing", http.StatusForbidden)
+	checkRouterOutput(t, router, "/tickets-func", http.StatusOK)
+	checkRouterOutput(t, router, "/tickets-middleware", http.StatusOK)
+	checkRouterOutput(t, router, "/billing-middleware", http.StatusForbidden)
+	checkRouterOutput(t, router, "/refunds", http.StatusForbidden)
+	checkRouterOutput(t, router, "/escalations", http.StatusOK)
+	checkRouterOutput(t, router, "/internal", http.StatusForbidden)
+}
+
+func TestGate_ProtectWithExpression(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClients([]*Client{
+		NewClient("billing-token").WithPermissions([]string{"billing", "read"}),
+		NewClient("suspended-token").WithPermissions([]string{"admin", "suspended"}),
+	}))
+	expression := "(admin || (billing && read)) && !suspended"
+
+	router := http.NewServeMux()
+	router.Handle("/invoices", gate.ProtectWithExpression(&testHandler{}, expression))
+	router.HandleFunc("/invoices-func", gate.ProtectFuncWithExpression(testHandlerFunc, expression))
+	router.Handle("/invoices-middleware", gate.ExpressionMiddleware(expression)(&testHandler{}))
+
+	checkRouterOutput := func(t *testing.T, router *http.ServeMux, url, token string, expectedResponseCode int) {
+		t.Run(strings.TrimPrefix(url, "/")+"-"+token, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", url, http.NoBody)
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+			}
+		})
+	}
+
+	for _, url := range []string{"/invoices", "/invoices-func", "/invoices-middleware"} {
+		checkRouterOutput(t, router, url, "billing-token", http.StatusOK)
+		checkRouterOutput(t, router, url, "suspended-token", http.StatusForbidden)
+	}
+}
+
+func TestGate_ProtectWithInvalidExpression(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected ProtectWithExpression to panic")
+		} else if message, _ := r.(string); !strings.Contains(message, "position 7") {
+			t.Errorf("expected panic message to mention the position of the error, got %v", r)
+		}
+	}()
+	New().ProtectWithExpression(&testHandler{}, "admin | support")
+}
+
+func TestGate_ProtectWithPermissionTemplates(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("mytoken").WithPermissions([]string{"org:acme:write", "org:globex:read"})))
+
+	router := http.NewServeMux()
+	router.Handle("POST /orgs/{org}/projects", gate.ProtectWithPermissions(&testHandler{}, []string{"org:{org}:write"}, WithPermissionTemplates()))
+	router.Handle("GET /orgs/{org}/projects", gate.ProtectWithAnyPermission(&testHandler{}, []string{"org:{org}:read", "org:{org}:write"}, WithPermissionTemplates()))
+	router.HandleFunc("DELETE /orgs/{org}", gate.ProtectFuncWithExpression(testHandlerFunc, "org:{org}:write && !org:{org}:read", WithPermissionTemplates()))
+
+	checkRouterOutput := func(t *testing.T, router *http.ServeMux, method, url string, expectedResponseCode int) {
+		t.Run(method+url, func(t *testing.T) {
+			request, _ := http.NewRequest(method, url, http.NoBody)
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "mytoken"))
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+			}
+		})
+	}
+
+	checkRouterOutput(t, router, "POST", "/orgs/acme/projects", http.StatusOK)
+	checkRouterOutput(t, router, "POST", "/orgs/globex/projects", http.StatusForbidden)
+	checkRouterOutput(t, router, "GET", "/orgs/acme/projects", http.StatusOK)
+	checkRouterOutput(t, router, "GET", "/orgs/globex/projects", http.StatusOK)
+	checkRouterOutput(t, router, "GET", "/orgs/initech/projects", http.StatusForbidden)
+	checkRouterOutput(t, router, "DELETE", "/orgs/acme", http.StatusOK)
+	checkRouterOutput(t, router, "DELETE", "/orgs/globex", http.StatusForbidden)
+	// Path values that could make the permission refer to another resource must not be authorized
+	checkRouterOutput(t, router, "POST", "/orgs/acme:write/projects", http.StatusForbidden)
+	checkRouterOutput(t, router, "DELETE", "/orgs/*", http.StatusForbidden)
+}
+
+func TestGate_ProtectWithInvalidPermissionTemplate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected ProtectWithPermissions to panic")
+		}
+	}()
+	New().ProtectWithPermissions(&testHandler{}, []string{"org:{org:write"}, WithPermissionTemplates())
+}
+
+func TestGate_ProtectWithPermissionsContainingBracesWithoutPermissionTemplates(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("mytoken").WithPermissions([]string{"{legacy}", "org:{org}:write"})))
+
+	router := http.NewServeMux()
+	// Without WithPermissionTemplates, permissions containing braces, even unbalanced ones, must be required as is
+	router.Handle("GET /legacy", gate.ProtectWithPermissions(&testHandler{}, []string{"{legacy}"}))
+	router.Handle("POST /orgs/{org}/projects", gate.ProtectWithPermissions(&testHandler{}, []string{"org:{org}:write"}))
+	router.Handle("GET /unbalanced", gate.ProtectWithPermissions(&testHandler{}, []string{"org:{org"}))
+
+	for _, scenario := range []struct {
+		method               string
+		url                  string
+		expectedResponseCode int
+	}{
+		{method: "GET", url: "/legacy", expectedResponseCode: http.StatusOK},
+		{method: "POST", url: "/orgs/acme/projects", expectedResponseCode: http.StatusOK},
+		{method: "GET", url: "/unbalanced", expectedResponseCode: http.StatusForbidden},
+	} {
+		t.Run(scenario.method+scenario.url, func(t *testing.T) {
+			request, _ := http.NewRequest(scenario.method, scenario.url, http.NoBody)
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "mytoken"))
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != scenario.expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.expectedResponseCode, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestGate_ProtectWithMethodPermissions(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClients([]*Client{
+		NewClient("reader-token").WithPermission("items:read"),
+		NewClient("writer-token").WithPermissions([]string{"items:read", "items:write"}),
+	}))
+	permissionsByMethod := map[string][]string{
+		http.MethodGet:    {"items:read"},
+		http.MethodPost:   {"items:write"},
+		"patch":           {"items:write"},
+		http.MethodDelete: {"items:write"},
+	}
+
+	router := http.NewServeMux()
+	router.Handle("/items", gate.ProtectWithMethodPermissions(&testHandler{}, permissionsByMethod))
+	router.HandleFunc("/items-func", gate.ProtectFuncWithMethodPermissions(testHandlerFunc, map[string][]string{http.MethodPost: {"items:write"}}))
+
+	checkRouterOutput := func(t *testing.T, router *http.ServeMux, method, url, token string, expectedResponseCode int) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest(method, url, http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, token, expectedResponseCode, responseRecorder.Code)
+		}
+		return responseRecorder
+	}
+
+	checkRouterOutput(t, router, http.MethodGet, "/items", "reader-token", http.StatusOK)
+	checkRouterOutput(t, router, http.MethodHead, "/items", "reader-token", http.StatusOK)
+	checkRouterOutput(t, router, http.MethodPost, "/items", "reader-token", http.StatusForbidden)
+	checkRouterOutput(t, router, http.MethodDelete, "/items", "reader-token", http.StatusForbidden)
+	checkRouterOutput(t, router, http.MethodGet, "/items", "writer-token", http.StatusOK)
+	checkRouterOutput(t, router, http.MethodPost, "/items", "writer-token", http.StatusOK)
+	checkRouterOutput(t, router, http.MethodDelete, "/items", "writer-token", http.StatusOK)
+	checkRouterOutput(t, router, http.MethodPatch, "/items", "writer-token", http.StatusOK)
+	checkRouterOutput(t, router, http.MethodGet, "/items", "bad-token", http.StatusUnauthorized)
+	responseRecorder := checkRouterOutput(t, router, http.MethodPut, "/items", "writer-token", http.StatusMethodNotAllowed)
+	if allow := responseRecorder.Header().Get("Allow"); allow != "DELETE, GET, HEAD, PATCH, POST" {
+		t.Errorf("expected Allow header to be %s, got %s", "DELETE, GET, HEAD, PATCH, POST", allow)
+	}
+	if responseBody := responseRecorder.Body.String(); responseBody != DefaultMethodNotAllowedResponseBody {
+		t.Errorf("expected response body to be %s, got %s", DefaultMethodNotAllowedResponseBody, responseBody)
+	}
+	checkRouterOutput(t, router, http.MethodPost, "/items-func", "writer-token", http.StatusOK)
+	responseRecorder = checkRouterOutput(t, router, http.MethodGet, "/items-func", "writer-token", http.StatusMethodNotAllowed)
+	if allow := responseRecorder.Header().Get("Allow"); allow != "POST" {
+		t.Errorf("expected Allow header to be %s, got %s", "POST", allow)
+	}
+}
+
+func TestGate_ProtectWithMethodPermissionsWhenMethodIsNotAllowed(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithToken("token")).WithRateLimit(1).WithProblemDetails()
+	handler := gate.ProtectWithMethodPermissions(&testHandler{}, map[string][]string{http.MethodPost: nil})
+	sendRequest := func() *httptest.ResponseRecorder {
+		request, _ := http.NewRequest(http.MethodPut, "/items", http.NoBody)
+		request.Header.Set("Accept", ProblemDetailsContentType)
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, request)
+		return responseRecorder
+	}
+	responseRecorder := sendRequest()
+	if responseRecorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, responseRecorder.Code)
+	}
+	if allow := responseRecorder.Header().Get("Allow"); allow != "POST" {
+		t.Errorf("expected Allow header to be %s, got %s", "POST", allow)
+	}
+	// The response must be written through the error handler
+	var problemDetails ProblemDetails
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&problemDetails); err != nil {
+		t.Fatalf("failed to decode problem details: %v", err)
+	}
+	if problemDetails.Status != http.StatusMethodNotAllowed || problemDetails.Detail != ErrMethodNotAllowed.Error() {
+		t.Errorf("expected problem details for status %d, got %+v", http.StatusMethodNotAllowed, problemDetails)
+	}
+	// The gate-wide rate limit must be checked before the method
+	if responseRecorder = sendRequest(); responseRecorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, responseRecorder.Code)
+	}
+}
+
+func TestGate_WithCustomMethodNotAllowedResponseBody(t *testing.T) {
+	gate := New().WithCustomMethodNotAllowedResponseBody([]byte("use POST"))
+	request, _ := http.NewRequest(http.MethodGet, "/items", http.NoBody)
+	responseRecorder := httptest.NewRecorder()
+	gate.ProtectWithMethodPermissions(&testHandler{}, map[string][]string{http.MethodPost: nil}).ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, responseRecorder.Code)
+	}
+	if responseBody := responseRecorder.Body.String(); responseBody != "use POST" {
+		t.Errorf("expected response body to be %s, got %s", "use POST", responseBody)
+	}
+}
+
+func TestGate_ProtectWithMethodPermissionsWithDuplicateMethod(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected ProtectWithMethodPermissions to panic")
+		}
+	}()
+	New().ProtectWithMethodPermissions(&testHandler{}, map[string][]string{"get": {"a"}, http.MethodGet: {"b"}})
 }
 
 func TestGate_ProtectWithPermissionWhenClientHasSufficientPermissions(t *testing.T) {
@@ -307,9 +559,9 @@ func TestGate_ProtectWithPermissionWhenClientHasInsufficientPermissions(t *testi
 	router.ServeHTTP(responseRecorder, request)
 
 	// Since the client registered directly in the AuthorizationService has the permission "mod" and the
-	// testHandler is protected by the permission "admin", the request should be not be authorized
-	if responseRecorder.Code != http.StatusUnauthorized {
-		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusUnauthorized, responseRecorder.Code)
+	// testHandler is protected by the permission "admin", the request should be forbidden
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusForbidden, responseRecorder.Code)
 	}
 }
 
@@ -341,9 +593,9 @@ func TestGate_PermissionMiddlewareWhenClientHasInsufficientPermissions(t *testin
 	router.ServeHTTP(responseRecorder, request)
 
 	// Since the client registered directly in the AuthorizationService has the permission "mod" and the
-	// testHandler is protected by the permission "admin", the request should be not be authorized
-	if responseRecorder.Code != http.StatusUnauthorized {
-		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusUnauthorized, responseRecorder.Code)
+	// testHandler is protected by the permission "admin", the request should be forbidden
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusForbidden, responseRecorder.Code)
 	}
 }
 
@@ -405,9 +657,9 @@ func TestGate_ProtectFuncWithPermissionWhenClientHasInsufficientPermissions(t *t
 	router.ServeHTTP(responseRecorder, request)
 
 	// Since the client registered directly in the AuthorizationService has the permission "mod" and the
-	// testHandler is protected by the permission "admin", the request should be not be authorized
-	if responseRecorder.Code != http.StatusUnauthorized {
-		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusUnauthorized, responseRecorder.Code)
+	// testHandler is protected by the permission "admin", the request should be forbidden
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusForbidden, responseRecorder.Code)
 	}
 }
 
@@ -429,6 +681,163 @@ func TestGate_WithCustomUnauthorizedResponseBody(t *testing.T) {
 	}
 }
 
+func TestGate_WithCustomForbiddenResponseBody(t *testing.T) {
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermission("read"))).
+		WithCustomUnauthorizedResponseBody([]byte("who are you?")).
+		WithCustomForbiddenResponseBody([]byte("you can't do that"))
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.ProtectWithPermission(&testHandler{}, "write"))
+
+	scenarios := []struct {
+		name                 string
+		token                string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{name: "missing-token", token: "", expectedResponseCode: http.StatusUnauthorized, expectedResponseBody: "who are you?"},
+		{name: "invalid-token", token: "bad-token", expectedResponseCode: http.StatusUnauthorized, expectedResponseBody: "who are you?"},
+		{name: "insufficient-permissions", token: "token", expectedResponseCode: http.StatusForbidden, expectedResponseBody: "you can't do that"},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+			if len(scenario.token) > 0 {
+				request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", scenario.token))
+			}
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != scenario.expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.expectedResponseCode, responseRecorder.Code)
+			}
+			if responseBody, _ := io.ReadAll(responseRecorder.Body); string(responseBody) != scenario.expectedResponseBody {
+				t.Errorf("%s %s should have returned %s, but returned %s instead", request.Method, request.URL, scenario.expectedResponseBody, string(responseBody))
+			}
+		})
+	}
+}
+
+func TestGate_WithErrorHandler(t *testing.T) {
+	var reasons []error
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermission("read"))).
+		WithErrorHandler(func(writer http.ResponseWriter, request *http.Request, reason error) {
+			reasons = append(reasons, reason)
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusTeapot)
+			_, _ = writer.Write([]byte(`{"error":"` + reason.Error() + `"}`))
+		})
+	router := http.NewServeMux()
+	router.Handle("/read", gate.ProtectWithPermission(&testHandler{}, "read", WithRouteRateLimiter(NewSlidingWindowRateLimiter(1, time.Minute))))
+	router.Handle("/write", gate.ProtectWithPermission(&testHandler{}, "write"))
+
+	scenarios := []struct {
+		name                 string
+		url                  string
+		token                string
+		expectedResponseCode int
+		expectedReason       error
+	}{
+		{name: "missing-token", url: "/read", token: "", expectedResponseCode: http.StatusTeapot, expectedReason: ErrMissingToken},
+		{name: "invalid-token", url: "/read", token: "bad-token", expectedResponseCode: http.StatusTeapot, expectedReason: ErrInvalidToken},
+		{name: "insufficient-permissions", url: "/write", token: "token", expectedResponseCode: http.StatusTeapot, expectedReason: ErrInsufficientPermissions},
+		{name: "authorized", url: "/read", token: "token", expectedResponseCode: http.StatusOK, expectedReason: nil},
+		{name: "rate-limited", url: "/read", token: "token", expectedResponseCode: http.StatusTeapot, expectedReason: ErrTooManyRequests},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			reasons = nil
+			request, _ := http.NewRequest("GET", scenario.url, http.NoBody)
+			if len(scenario.token) > 0 {
+				request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", scenario.token))
+			}
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != scenario.expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.expectedResponseCode, responseRecorder.Code)
+			}
+			if scenario.expectedReason == nil {
+				if len(reasons) != 0 {
+					t.Errorf("expected error handler not to be called, but it was called with %v", reasons)
+				}
+				return
+			}
+			if len(reasons) != 1 || !errors.Is(reasons[0], scenario.expectedReason) {
+				t.Errorf("expected error handler to be called once with %v, got %v", scenario.expectedReason, reasons)
+			}
+			if contentType := responseRecorder.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected Content-Type to be %s, got %s", "application/json", contentType)
+			}
+		})
+	}
+}
+
+func TestGate_WithErrorHandlerWhenQuotaExceeded(t *testing.T) {
+	var reason error
+	var retryAfter string
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithToken("token")).
+		WithQuota(NewQuota(1, QuotaPeriodDaily)).
+		WithErrorHandler(func(writer http.ResponseWriter, request *http.Request, err error) {
+			// Headers must have been set before the error handler is called
+			reason, retryAfter = err, writer.Header().Get(RetryAfterHeader)
+			writer.WriteHeader(http.StatusPaymentRequired)
+		})
+	handler := gate.Protect(&testHandler{})
+	for _, expectedResponseCode := range []int{http.StatusOK, http.StatusPaymentRequired} {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "token"))
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+	if !errors.Is(reason, ErrQuotaExceeded) {
+		t.Errorf("expected reason to be %v, got %v", ErrQuotaExceeded, reason)
+	}
+	if len(retryAfter) == 0 {
+		t.Errorf("expected %s header to be set before the error handler is called", RetryAfterHeader)
+	}
+}
+
+func TestGate_ProtectWithWWWAuthenticateHeader(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermission("org:acme:read"))).WithRealm("example")
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+	router.Handle("POST /orgs/{org}", gate.ProtectWithPermissions(&testHandler{}, []string{"org:{org}:read", "org:{org}:write"}, WithPermissionTemplates()))
+
+	scenarios := []struct {
+		name                          string
+		method                        string
+		url                           string
+		token                         string
+		expectedResponseCode          int
+		expectedWWWAuthenticateHeader string
+	}{
+		{name: "missing-token", method: "GET", url: "/handle", token: "", expectedResponseCode: http.StatusUnauthorized, expectedWWWAuthenticateHeader: `Bearer realm="example"`},
+		{name: "invalid-token", method: "GET", url: "/handle", token: "bad-token", expectedResponseCode: http.StatusUnauthorized, expectedWWWAuthenticateHeader: `Bearer realm="example", error="invalid_token"`},
+		{name: "insufficient-permissions", method: "POST", url: "/orgs/acme", token: "token", expectedResponseCode: http.StatusForbidden, expectedWWWAuthenticateHeader: `Bearer realm="example", error="insufficient_scope", scope="org:acme:read org:acme:write"`},
+		{name: "authorized", method: "GET", url: "/handle", token: "token", expectedResponseCode: http.StatusOK, expectedWWWAuthenticateHeader: ""},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			request, _ := http.NewRequest(scenario.method, scenario.url, http.NoBody)
+			if len(scenario.token) > 0 {
+				request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", scenario.token))
+			}
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != scenario.expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.expectedResponseCode, responseRecorder.Code)
+			}
+			if header := responseRecorder.Header().Get(WWWAuthenticateHeader); header != scenario.expectedWWWAuthenticateHeader {
+				t.Errorf("expected %s header to be %s, got %s", WWWAuthenticateHeader, scenario.expectedWWWAuthenticateHeader, header)
+			}
+		})
+	}
+}
+
 func TestGate_ProtectWithNoAuthorizationService(t *testing.T) {
 	gate := New()
 	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
@@ -500,6 +909,15 @@ func TestGate_WithCustomTokenExtractor(t *testing.T) {
 		if r.Context().Value(DataContextKey) != TestProviderClientData {
 			t.Errorf("data should have been passed to the request context")
 		}
+		if token, _ := TokenFromContext(r.Context()); token != TestProviderClientToken {
+			t.Errorf("token should have been retrievable using TokenFromContext")
+		}
+		if data, _ := DataFromContext[string](r.Context()); data != TestProviderClientData {
+			t.Errorf("data should have been retrievable using DataFromContext")
+		}
+		if client, _ := ClientFromContext(r.Context()); client == nil || !client.HasPermission(FirstTestProviderClientPermission) {
+			t.Errorf("client should have been passed to the request context")
+		}
 		w.WriteHeader(http.StatusOK)
 	}))
 	router.ServeHTTP(responseRecorder, request)
@@ -528,6 +946,15 @@ func TestGateWithCustomHeader(t *testing.T) {
 		if r.Context().Value(DataContextKey) != TestProviderClientData {
 			t.Errorf("data should have been passed to the request context")
 		}
+		if token, _ := TokenFromContext(r.Context()); token != TestProviderClientToken {
+			t.Errorf("token should have been retrievable using TokenFromContext")
+		}
+		if data, _ := DataFromContext[string](r.Context()); data != TestProviderClientData {
+			t.Errorf("data should have been retrievable using DataFromContext")
+		}
+		if client, _ := ClientFromContext(r.Context()); client == nil || !client.HasPermission(FirstTestProviderClientPermission) {
+			t.Errorf("client should have been passed to the request context")
+		}
 		w.WriteHeader(http.StatusOK)
 	}))
 	router.ServeHTTP(responseRecorder, request)
@@ -536,3 +963,634 @@ func TestGateWithCustomHeader(t *testing.T) {
 		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
 	}
 }
+
+func TestGate_ProtectWithClientRateLimit(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithTokens([]string{"token-1", "token-2"})).WithClientRateLimit(2)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(token string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, token, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+
+	checkResponseCode("token-1", http.StatusOK)
+	checkResponseCode("token-1", http.StatusOK)
+	checkResponseCode("token-1", http.StatusTooManyRequests)
+	// token-2 has its own quota, so it should not be affected by token-1 exceeding its quota
+	checkResponseCode("token-2", http.StatusOK)
+	checkResponseCode("token-2", http.StatusOK)
+	checkResponseCode("token-2", http.StatusTooManyRequests)
+	// Unauthorized requests should still return 401 rather than 429
+	checkResponseCode("bad-token", http.StatusUnauthorized)
+
+	// Wait for rate limit time window to pass
+	time.Sleep(time.Second)
+
+	checkResponseCode("token-1", http.StatusOK)
+}
+
+func TestGate_ProtectWithRateLimitCountsUnauthorizedRequests(t *testing.T) {
+	var clientProviderCalls int
+	clientProvider := NewClientProvider(func(token string) *Client {
+		clientProviderCalls++
+		return nil
+	})
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithToken("good-token").WithClientProvider(clientProvider)).WithRateLimit(2)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(token string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, token, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+	checkResponseCode("bad-token", http.StatusUnauthorized)
+	checkResponseCode("good-token", http.StatusOK)
+	// The gate-wide rate limit is shared by every request, whether it has a valid token or not
+	checkResponseCode("bad-token", http.StatusTooManyRequests)
+	checkResponseCode("good-token", http.StatusTooManyRequests)
+	if clientProviderCalls != 1 {
+		t.Errorf("expected the client provider to be called %d time, got %d", 1, clientProviderCalls)
+	}
+}
+
+func TestGate_ProtectWithIPRateLimit(t *testing.T) {
+	gate := New().WithIPRateLimit(2)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(remoteAddr string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.RemoteAddr = remoteAddr
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s from %s should have returned %d, but returned %d instead", request.Method, request.URL, remoteAddr, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+
+	checkResponseCode("192.0.2.1:1234", http.StatusOK)
+	checkResponseCode("192.0.2.1:5678", http.StatusOK)
+	checkResponseCode("192.0.2.1:1234", http.StatusTooManyRequests)
+	// 192.0.2.2 has its own quota, so it should not be affected by 192.0.2.1 exceeding its quota
+	checkResponseCode("192.0.2.2:1234", http.StatusOK)
+}
+
+func TestGate_ProtectWithIPRateLimitAndAuthorizationService(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithToken("good-token")).WithIPRateLimit(1)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(token string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.RemoteAddr = "192.0.2.1:1234"
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, token, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+
+	checkResponseCode("bad-token", http.StatusUnauthorized)
+	checkResponseCode("bad-token", http.StatusTooManyRequests)
+	// Authorized clients are not subject to the per-IP rate limit
+	checkResponseCode("good-token", http.StatusOK)
+	checkResponseCode("good-token", http.StatusOK)
+}
+
+func TestGate_ProtectWithTokenBucketRateLimiter(t *testing.T) {
+	gate := New().WithRateLimiter(NewTokenBucketRateLimiter(1, 3))
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	for i := 0; i < 3; i++ {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
+		}
+	}
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusTooManyRequests {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusTooManyRequests, responseRecorder.Code)
+	}
+}
+
+func TestGate_ProtectWithClientRateLimiter(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithTokens([]string{"token-1", "token-2"})).WithClientRateLimiter(func(_ string) Limiter {
+		return NewTokenBucketRateLimiter(1, 1)
+	})
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	for _, scenario := range []struct {
+		token                string
+		expectedResponseCode int
+	}{
+		{"token-1", http.StatusOK},
+		{"token-1", http.StatusTooManyRequests},
+		{"token-2", http.StatusOK},
+		{"token-2", http.StatusTooManyRequests},
+	} {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", scenario.token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != scenario.expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.token, scenario.expectedResponseCode, responseRecorder.Code)
+		}
+	}
+}
+
+func TestGate_ProtectWithSlidingWindowRateLimit(t *testing.T) {
+	gate := New().WithSlidingWindowRateLimit(2, time.Minute)
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	for i, expectedResponseCode := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("request #%d to %s %s should have returned %d, but returned %d instead", i+1, request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+}
+
+func TestGate_ProtectWithRateLimitHeaders(t *testing.T) {
+	gate := New().WithRateLimit(2)
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	for i, expectedRemaining := range []string{"1", "0"} {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
+		}
+		if limit := responseRecorder.Header().Get(RateLimitLimitHeader); limit != "2" {
+			t.Errorf("request #%d: expected %s header to be %s, got %s", i+1, RateLimitLimitHeader, "2", limit)
+		}
+		if remaining := responseRecorder.Header().Get(RateLimitRemainingHeader); remaining != expectedRemaining {
+			t.Errorf("request #%d: expected %s header to be %s, got %s", i+1, RateLimitRemainingHeader, expectedRemaining, remaining)
+		}
+		if reset := responseRecorder.Header().Get(RateLimitResetHeader); reset != "1" {
+			t.Errorf("request #%d: expected %s header to be %s, got %s", i+1, RateLimitResetHeader, "1", reset)
+		}
+		if retryAfter := responseRecorder.Header().Get(RetryAfterHeader); len(retryAfter) != 0 {
+			t.Errorf("request #%d: expected no %s header, got %s", i+1, RetryAfterHeader, retryAfter)
+		}
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusTooManyRequests {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusTooManyRequests, responseRecorder.Code)
+	}
+	if remaining := responseRecorder.Header().Get(RateLimitRemainingHeader); remaining != "0" {
+		t.Errorf("expected %s header to be %s, got %s", RateLimitRemainingHeader, "0", remaining)
+	}
+	if retryAfter := responseRecorder.Header().Get(RetryAfterHeader); retryAfter != "1" {
+		t.Errorf("expected %s header to be %s, got %s", RetryAfterHeader, "1", retryAfter)
+	}
+}
+
+func TestGate_ProtectWithoutRateLimitHasNoRateLimitHeaders(t *testing.T) {
+	gate := New()
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	responseRecorder := httptest.NewRecorder()
+	gate.Protect(&testHandler{}).ServeHTTP(responseRecorder, request)
+	if limit := responseRecorder.Header().Get(RateLimitLimitHeader); len(limit) != 0 {
+		t.Errorf("expected no %s header, got %s", RateLimitLimitHeader, limit)
+	}
+}
+
+func TestGate_ProtectWithPermissionBasedClientRateLimit(t *testing.T) {
+	authorizationService := NewAuthorizationService().WithClients([]*Client{
+		NewClient("free-token"),
+		NewClient("pro-token").WithPermission("tier:pro"),
+		NewClient("enterprise-token").WithPermissions([]string{"tier:pro", "tier:enterprise"}),
+		NewClient("internal-token").WithPermissions([]string{"tier:pro", "internal"}),
+	})
+	gate := New().WithAuthorizationService(authorizationService).WithPermissionBasedClientRateLimit(map[string]int{
+		"tier:pro":        2,
+		"tier:enterprise": 3,
+		"internal":        0,
+	}, 1)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	scenarios := []struct {
+		token                 string
+		expectedAllowedCount  int
+		expectRateLimitHeader bool
+	}{
+		{token: "free-token", expectedAllowedCount: 1, expectRateLimitHeader: true},
+		{token: "pro-token", expectedAllowedCount: 2, expectRateLimitHeader: true},
+		{token: "enterprise-token", expectedAllowedCount: 3, expectRateLimitHeader: true},
+		{token: "internal-token", expectedAllowedCount: 10, expectRateLimitHeader: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.token, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+				request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", scenario.token))
+				responseRecorder := httptest.NewRecorder()
+				router.ServeHTTP(responseRecorder, request)
+				expectedResponseCode := http.StatusOK
+				if i >= scenario.expectedAllowedCount {
+					expectedResponseCode = http.StatusTooManyRequests
+				}
+				if responseRecorder.Code != expectedResponseCode {
+					t.Fatalf("request #%d should have returned %d, but returned %d instead", i+1, expectedResponseCode, responseRecorder.Code)
+				}
+				if hasRateLimitHeader := len(responseRecorder.Header().Get(RateLimitLimitHeader)) > 0; hasRateLimitHeader != scenario.expectRateLimitHeader {
+					t.Fatalf("request #%d: expected presence of %s header to be %v", i+1, RateLimitLimitHeader, scenario.expectRateLimitHeader)
+				}
+			}
+		})
+	}
+}
+
+func TestGate_ProtectWithClientRateLimitFunc(t *testing.T) {
+	plan := "free"
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithToken("token")).WithClientRateLimitFunc(func(client *Client) int {
+		if plan == "pro" {
+			return 3
+		}
+		return 1
+	})
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "token"))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+
+	checkResponseCode(http.StatusOK)
+	checkResponseCode(http.StatusTooManyRequests)
+	// Upgrading the client's plan should apply the new rate limit right away
+	plan = "pro"
+	checkResponseCode(http.StatusOK)
+	checkResponseCode(http.StatusOK)
+	checkResponseCode(http.StatusOK)
+	checkResponseCode(http.StatusTooManyRequests)
+}
+
+func TestGate_ProtectWithRouteRateLimit(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermissions([]string{"read", "export"}))).WithRateLimit(100)
+	router := http.NewServeMux()
+	router.Handle("/export", gate.ProtectWithPermissions(&testHandler{}, []string{"export"}, WithRouteRateLimit(1)))
+	router.Handle("/read", gate.ProtectWithPermissions(&testHandler{}, []string{"read"}))
+	router.HandleFunc("/export-func", gate.ProtectFuncWithPermission(testHandlerFunc, "export", WithRouteRateLimit(2)))
+	router.Handle("/export-middleware", gate.PermissionMiddlewareWithOptions([]string{"export"}, WithRouteRateLimit(1))(&testHandler{}))
+	router.Handle("/protect", gate.Protect(&testHandler{}, WithRouteRateLimit(1)))
+	router.HandleFunc("/protect-func", gate.ProtectFunc(testHandlerFunc, WithRouteRateLimit(1)))
+	router.Handle("/any-permission-middleware", gate.AnyPermissionMiddlewareWithOptions([]string{"admin", "export"}, WithRouteRateLimit(1))(&testHandler{}))
+
+	checkResponseCode := func(url string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", url, http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "token"))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+
+	checkResponseCode("/export", http.StatusOK)
+	checkResponseCode("/export", http.StatusTooManyRequests)
+	for i := 0; i < 10; i++ {
+		// The route rate limit of /export must not affect /read
+		checkResponseCode("/read", http.StatusOK)
+	}
+	checkResponseCode("/export-func", http.StatusOK)
+	checkResponseCode("/export-func", http.StatusOK)
+	checkResponseCode("/export-func", http.StatusTooManyRequests)
+	checkResponseCode("/export-middleware", http.StatusOK)
+	checkResponseCode("/export-middleware", http.StatusTooManyRequests)
+	for _, url := range []string{"/protect", "/protect-func", "/any-permission-middleware"} {
+		checkResponseCode(url, http.StatusOK)
+		checkResponseCode(url, http.StatusTooManyRequests)
+	}
+}
+
+func TestGate_ProtectWithRateLimitRefundsRejectedRequests(t *testing.T) {
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithTokens([]string{"token-1", "token-2"})).
+		WithClientRateLimiter(func(key string) Limiter { return NewSlidingWindowRateLimiter(2, time.Minute) }).
+		WithRateLimiter(NewSlidingWindowRateLimiter(3, time.Minute))
+	router := http.NewServeMux()
+	router.Handle("/limited", gate.ProtectWithPermissions(&testHandler{}, nil, WithRouteRateLimiter(NewSlidingWindowRateLimiter(1, time.Minute))))
+	router.Handle("/unlimited", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(url, token string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", url, http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, token, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+	checkResponseCode("/limited", "token-1", http.StatusOK)
+	// Rejected by the route rate limit, so the units consumed from the gate-wide and client rate limits are refunded
+	checkResponseCode("/limited", "token-1", http.StatusTooManyRequests)
+	checkResponseCode("/unlimited", "token-1", http.StatusOK)
+	// Rejected by the client rate limit, so the unit consumed from the gate-wide rate limit is refunded
+	checkResponseCode("/unlimited", "token-1", http.StatusTooManyRequests)
+	checkResponseCode("/unlimited", "token-2", http.StatusOK)
+	checkResponseCode("/unlimited", "token-2", http.StatusTooManyRequests)
+}
+
+func TestGate_ProtectWithCost(t *testing.T) {
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermissions([]string{"read", "export"}))).
+		WithClientRateLimiter(func(_ string) Limiter {
+			// The refill rate is low enough for the bucket to not be refilled during the test
+			return NewTokenBucketRateLimiter(0.001, 100)
+		})
+	router := http.NewServeMux()
+	router.Handle("/export", gate.ProtectWithPermissions(&testHandler{}, []string{"export"}, WithCost(50)))
+	router.Handle("/read", gate.ProtectWithPermissions(&testHandler{}, []string{"read"}))
+	router.Handle("/search", gate.PermissionMiddlewareWithOptions([]string{"read"}, WithCostFunc(func(request *http.Request) int {
+		return len(request.URL.Query()["q"])
+	}))(&testHandler{}))
+
+	checkResponseCode := func(url string, expectedResponseCode int) {
+		request, _ := http.NewRequest("GET", url, http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", "token"))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, expectedResponseCode, responseRecorder.Code)
+		}
+	}
+
+	checkResponseCode("/export", http.StatusOK)
+	for i := 0; i < 10; i++ {
+		checkResponseCode("/read", http.StatusOK)
+	}
+	// Only 40 units are left, which is not enough for another export
+	checkResponseCode("/export", http.StatusTooManyRequests)
+	checkResponseCode("/search?q=a&q=b&q=c", http.StatusOK)
+	// 37 units are left
+	for i := 0; i < 37; i++ {
+		checkResponseCode("/read", http.StatusOK)
+	}
+	checkResponseCode("/read", http.StatusTooManyRequests)
+	// A request with a cost of 0 does not consume any quota
+	checkResponseCode("/search", http.StatusOK)
+}
+
+func TestGate_ProtectWithRateLimitMaxWait(t *testing.T) {
+	gate := New().WithRateLimiter(NewTokenBucketRateLimiter(20, 1)).WithRateLimitMaxWait(200 * time.Millisecond)
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
+		}
+	}
+	// The first request goes through right away, but the two others must wait for a token to be added to the bucket
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected requests to have been delayed, but they took only %s", elapsed)
+	}
+}
+
+func TestGate_ProtectWithRateLimitMaxWaitExceeded(t *testing.T) {
+	gate := New().WithRateLimit(1).WithRateLimitMaxWait(50 * time.Millisecond)
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
+	}
+	// The quota will only be available in ~1s, which is longer than the maximum wait
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusTooManyRequests {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusTooManyRequests, responseRecorder.Code)
+	}
+}
+
+func TestGate_ProtectWithRateLimitMaxWaitSharedByEveryRateLimit(t *testing.T) {
+	gateLimiter, routeLimiter := NewTokenBucketRateLimiter(4, 1), NewTokenBucketRateLimiter(2, 1)
+	gate := New().WithRateLimiter(gateLimiter).WithRateLimitMaxWait(300 * time.Millisecond)
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.ProtectWithPermissions(&testHandler{}, nil, WithRouteRateLimiter(routeLimiter)))
+	// The gate-wide rate limit will be available again in ~250ms, and the route rate limit in ~500ms
+	gateLimiter.Take()
+	routeLimiter.Take()
+
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	responseRecorder := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(responseRecorder, request)
+	// Each rate limit alone is available within the maximum wait, but not both of them one after the other
+	if responseRecorder.Code != http.StatusTooManyRequests {
+		t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusTooManyRequests, responseRecorder.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("expected the request to wait for at most ~300ms, waited %s", elapsed)
+	}
+}
+
+func TestGate_ProtectWithConcurrencyLimit(t *testing.T) {
+	scenarios := []struct {
+		name                 string
+		gate                 *Gate
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "default-status-code",
+			gate:                 New().WithConcurrencyLimit(1),
+			expectedStatusCode:   http.StatusTooManyRequests,
+			expectedResponseBody: DefaultTooManyConcurrentRequestsResponseBody,
+		},
+		{
+			name:                 "custom-status-code",
+			gate:                 New().WithConcurrencyLimit(1).WithConcurrencyLimitStatusCode(http.StatusServiceUnavailable),
+			expectedStatusCode:   http.StatusServiceUnavailable,
+			expectedResponseBody: DefaultTooManyConcurrentRequestsResponseBody,
+		},
+		{
+			name:                 "custom-status-code-and-response-body",
+			gate:                 New().WithConcurrencyLimit(1).WithConcurrencyLimitStatusCode(http.StatusServiceUnavailable).WithCustomTooManyConcurrentRequestsResponseBody([]byte("server is busy")),
+			expectedStatusCode:   http.StatusServiceUnavailable,
+			expectedResponseBody: "server is busy",
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			started, unblock, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+			handler := scenario.gate.ProtectFunc(func(writer http.ResponseWriter, request *http.Request) {
+				if request.URL.Path == "/slow" {
+					close(started)
+					<-unblock
+				}
+				writer.WriteHeader(http.StatusOK)
+			})
+			go func() {
+				defer close(done)
+				request, _ := http.NewRequest("GET", "/slow", http.NoBody)
+				handler.ServeHTTP(httptest.NewRecorder(), request)
+			}()
+			<-started
+
+			request, _ := http.NewRequest("GET", "/fast", http.NoBody)
+			responseRecorder := httptest.NewRecorder()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != scenario.expectedStatusCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.expectedStatusCode, responseRecorder.Code)
+			}
+			if responseBody, _ := io.ReadAll(responseRecorder.Body); string(responseBody) != scenario.expectedResponseBody {
+				t.Errorf("%s %s should have returned %s, but returned %s instead", request.Method, request.URL, scenario.expectedResponseBody, string(responseBody))
+			}
+
+			close(unblock)
+			<-done
+			// Now that the slow request is done, its slot should have been released
+			responseRecorder = httptest.NewRecorder()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != http.StatusOK {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, http.StatusOK, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestGate_ProtectWithConcurrencyLimitReportsRefundedRateLimit(t *testing.T) {
+	gate := New().WithRateLimit(5).WithConcurrencyLimit(1)
+	// Occupy the only slot, as a request in flight would
+	gate.concurrencyLimiter.acquire("")
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	responseRecorder := httptest.NewRecorder()
+	gate.ProtectFunc(testHandlerFunc).ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, responseRecorder.Code)
+	}
+	// The quota consumed by the rejected request was refunded, so it must not be reported as consumed
+	if remaining := responseRecorder.Header().Get(RateLimitRemainingHeader); remaining != "5" {
+		t.Errorf("expected header %s to be %s, got %s", RateLimitRemainingHeader, "5", remaining)
+	}
+}
+
+func TestGate_ProtectWithClientConcurrencyLimit(t *testing.T) {
+	gate := New().WithAuthorizationService(NewAuthorizationService().WithTokens([]string{"token-1", "token-2"})).WithClientConcurrencyLimit(1)
+	started, unblock, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	handler := gate.ProtectFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/slow" {
+			close(started)
+			<-unblock
+		}
+		writer.WriteHeader(http.StatusOK)
+	})
+	newRequest := func(url, token string) *http.Request {
+		request, _ := http.NewRequest("GET", url, http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		return request
+	}
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("/slow", "token-1"))
+	}()
+	<-started
+	defer func() {
+		close(unblock)
+		<-done
+	}()
+
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, newRequest("/fast", "token-1"))
+	if responseRecorder.Code != http.StatusTooManyRequests {
+		t.Errorf("request with token-1 should have returned %d, but returned %d instead", http.StatusTooManyRequests, responseRecorder.Code)
+	}
+	// token-2 has its own slots, so it should not be affected by token-1's request in flight
+	responseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, newRequest("/fast", "token-2"))
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("request with token-2 should have returned %d, but returned %d instead", http.StatusOK, responseRecorder.Code)
+	}
+}
+
+func TestGate_ProtectWithQuota(t *testing.T) {
+	dailyQuota, monthlyQuota := NewQuota(3, QuotaPeriodDaily), NewQuota(2, QuotaPeriodMonthly)
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithTokens([]string{"token-1", "token-2"})).
+		WithQuota(dailyQuota).
+		WithQuota(monthlyQuota).
+		WithCustomQuotaExceededResponseBody([]byte("upgrade your plan"))
+	router := http.NewServeMux()
+	router.Handle("/handle", gate.Protect(&testHandler{}))
+
+	checkResponseCode := func(token string, expectedResponseCode int) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != expectedResponseCode {
+			t.Errorf("%s %s with token %s should have returned %d, but returned %d instead", request.Method, request.URL, token, expectedResponseCode, responseRecorder.Code)
+		}
+		return responseRecorder
+	}
+
+	checkResponseCode("token-1", http.StatusOK)
+	checkResponseCode("token-1", http.StatusOK)
+	responseRecorder := checkResponseCode("token-1", http.StatusTooManyRequests)
+	if responseBody, _ := io.ReadAll(responseRecorder.Body); string(responseBody) != "upgrade your plan" {
+		t.Errorf("expected response body to be %s, got %s", "upgrade your plan", string(responseBody))
+	}
+	if len(responseRecorder.Header().Get(RetryAfterHeader)) == 0 {
+		t.Errorf("expected %s header to be set", RetryAfterHeader)
+	}
+	checkResponseCode("token-2", http.StatusOK)
+	checkResponseCode("bad-token", http.StatusUnauthorized)
+
+	// The daily quota must have been refunded when the monthly quota was exhausted
+	if usage, _ := dailyQuota.Usage("token-1"); usage.Used != 2 {
+		t.Errorf("expected daily usage of token-1 to be %d, got %d", 2, usage.Used)
+	}
+	if usage, _ := monthlyQuota.Usage("token-1"); usage.Used != 2 {
+		t.Errorf("expected monthly usage of token-1 to be %d, got %d", 2, usage.Used)
+	}
+}
+
+func TestGate_WithQuotaWithSamePeriodAndKeyPrefix(t *testing.T) {
+	gate := New().WithQuota(NewQuota(10, QuotaPeriodDaily)).WithQuota(NewQuota(10, QuotaPeriodDaily).WithKeyPrefix("other"))
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithQuota to panic")
+		}
+	}()
+	gate.WithQuota(NewQuota(20, QuotaPeriodDaily))
+}
@@ -0,0 +1,131 @@
+package g8
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// minimumWaitInterval is the minimum duration waited between two attempts by waitForLimiter, which prevents busy
+// looping if a Limiter does not report a RetryAfter
+const minimumWaitInterval = 10 * time.Millisecond
+
+var (
+	// ErrWaitExceedsDeadline is the error returned by Wait if the rate limit quota will not be available before the
+	// context's deadline
+	ErrWaitExceedsDeadline = errors.New("rate limit quota will not be available before the context's deadline")
+
+	// ErrCostExceedsLimit is the error returned by WaitN if n is greater than the Limiter's limit, in which case the
+	// quota would never be available
+	ErrCostExceedsLimit = errors.New("cost exceeds the rate limit quota")
+)
+
+// Limiter is the interface that rate limiters used by Gate must implement.
+//
+// g8 comes with a few implementations:
+//   - RateLimiter, a fixed window rate limiter
+//   - TokenBucketRateLimiter, a token bucket rate limiter that allows bursts
+//   - SlidingWindowRateLimiter, a sliding window counter rate limiter that supports windows of any duration
+//   - StoreRateLimiter, a fixed window rate limiter that keeps track of executions in a Store shared across instances
+type Limiter interface {
+	// TakeN updates the state of the limiter by consuming n units of the rate limit quota if at least n units are
+	// left, and returns the outcome of the attempt, including whether it was successful or not.
+	//
+	// An attempt either consumes all n units or none of them. A negative n must be treated as 0, so that it cannot be
+	// used to give back units that were never consumed.
+	TakeN(n int) RateLimitResult
+}
+
+// RefundableLimiter is a Limiter whose consumed quota can be given back.
+//
+// When a request goes through several limiters (e.g. a client rate limit, a route rate limit and a gate-wide rate
+// limit) and one of them rejects it, Gate refunds the units consumed from the limiters that implement this interface,
+// so that requests that were never served don't count toward their quota. Every Limiter provided by g8 implements it.
+type RefundableLimiter interface {
+	Limiter
+
+	// RefundN gives back n units of the rate limit quota consumed by a successful call to TakeN, whose
+	// RateLimitResult.Window is passed as parameter.
+	//
+	// If the window the units were consumed from has passed since, the refund must be ignored rather than applied to
+	// the current window, since it would otherwise allow more executions than the limit.
+	RefundN(n int, window int64)
+}
+
+// RateLimitResult is the outcome of an attempt to consume a Limiter's quota.
+//
+// Gate uses it to populate the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and Retry-After headers.
+type RateLimitResult struct {
+	// Allowed is whether the attempt was successful (i.e. the rate limit quota had not been reached)
+	Allowed bool
+
+	// Limit is the maximum number of executions allowed by the Limiter's quota
+	Limit int
+
+	// Remaining is the number of executions left in the Limiter's quota after the attempt
+	Remaining int
+
+	// ResetAfter is the duration after which the Limiter's quota will be fully available again
+	ResetAfter time.Duration
+
+	// RetryAfter is the duration to wait before the next attempt may be successful.
+	// It is always 0 if Allowed is true.
+	RetryAfter time.Duration
+
+	// Window identifies the window of the Limiter's quota that the attempt consumed units from, which is passed back
+	// to RefundableLimiter.RefundN. Its value is only meaningful to the Limiter that returned it, and it is 0 for
+	// limiters that have no windows (e.g. TokenBucketRateLimiter).
+	Window int64
+}
+
+// isMoreRestrictiveThan checks whether a result is more restrictive than another result, which is used to determine
+// which result should be reported to the client when more than one Limiter is involved.
+func (result RateLimitResult) isMoreRestrictiveThan(other RateLimitResult) bool {
+	if result.Allowed != other.Allowed {
+		return !result.Allowed
+	}
+	if !result.Allowed {
+		return result.RetryAfter > other.RetryAfter
+	}
+	if result.Remaining != other.Remaining {
+		return result.Remaining < other.Remaining
+	}
+	return result.ResetAfter > other.ResetAfter
+}
+
+// waitForLimiter blocks until n units of the Limiter's quota are successfully consumed or the context is done,
+// whichever comes first, and returns the RateLimitResult of the last attempt.
+//
+// If the context has a deadline and the Limiter reports that its quota will not be available before said deadline,
+// ErrWaitExceedsDeadline is returned right away rather than waiting for nothing. Likewise, if n is greater than the
+// Limiter's limit, ErrCostExceedsLimit is returned right away.
+func waitForLimiter(ctx context.Context, limiter Limiter, n int) (RateLimitResult, error) {
+	for {
+		result := limiter.TakeN(n)
+		if result.Allowed {
+			return result, nil
+		}
+		if n > result.Limit {
+			return result, ErrCostExceedsLimit
+		}
+		waitDuration := max(minimumWaitInterval, result.RetryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < waitDuration {
+			return result, ErrWaitExceedsDeadline
+		}
+		timer := time.NewTimer(waitDuration)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Make sure that the rate limiters provided by g8 are compatible with the interfaces
+var (
+	_ RefundableLimiter = (*RateLimiter)(nil)
+	_ RefundableLimiter = (*TokenBucketRateLimiter)(nil)
+	_ RefundableLimiter = (*SlidingWindowRateLimiter)(nil)
+	_ RefundableLimiter = (*StoreRateLimiter)(nil)
+)
@@ -0,0 +1,34 @@
+package g8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_TakeNWithNegativeN(t *testing.T) {
+	scenarios := []struct {
+		name    string
+		limiter Limiter
+	}{
+		{name: "rate-limiter", limiter: NewRateLimiter(2)},
+		{name: "token-bucket", limiter: NewTokenBucketRateLimiter(1, 2)},
+		{name: "sliding-window", limiter: NewSlidingWindowRateLimiter(2, time.Minute)},
+		{name: "store", limiter: NewStoreRateLimiter(NewMemoryStore(10), "key", 2, time.Minute)},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			// A negative n must not give back units, which would allow more executions than the limit
+			if result := scenario.limiter.TakeN(-5); !result.Allowed || result.Remaining > result.Limit {
+				t.Errorf("expected a negative n to be treated as 0, got %+v", result)
+			}
+			for i := 0; i < 2; i++ {
+				if !scenario.limiter.TakeN(1).Allowed {
+					t.Errorf("expected attempt #%d to be allowed", i+1)
+				}
+			}
+			if scenario.limiter.TakeN(1).Allowed {
+				t.Error("expected the attempt exceeding the limit to be rejected")
+			}
+		})
+	}
+}
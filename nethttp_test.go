@@ -170,8 +170,8 @@ func TestNetHTTPCompleteServerExample(t *testing.T) {
 			name:           "Admin endpoint - public token (insufficient permissions)",
 			endpoint:       "/api/admin/users",
 			token:          "public-token",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "token is missing or invalid",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "insufficient permissions",
 		},
 		{
 			name:           "Admin endpoint - admin token",
@@ -205,8 +205,8 @@ func TestNetHTTPCompleteServerExample(t *testing.T) {
 			name:           "Read data endpoint - admin token (no read permission)",
 			endpoint:       "/api/read-data",
 			token:          "admin-token",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "token is missing or invalid",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "insufficient permissions",
 		},
 	}
 
@@ -305,8 +305,8 @@ func TestNetHTTPHandleVsHandleFunc(t *testing.T) {
 			name:           "Admin handler - user token (insufficient permissions)",
 			endpoint:       "/admin",
 			token:          "user-token",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "token is missing or invalid",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "insufficient permissions",
 		},
 		{
 			name:           "Admin handler - admin token",
@@ -326,8 +326,8 @@ func TestNetHTTPHandleVsHandleFunc(t *testing.T) {
 			name:           "User handler - admin token (no user permission)",
 			endpoint:       "/user",
 			token:          "admin-token",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "token is missing or invalid",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "insufficient permissions",
 		},
 	}
 
@@ -386,6 +386,9 @@ func TestNetHTTPWithCustomTokenExtractor(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/data", gate.ProtectFunc(func(w http.ResponseWriter, r *http.Request) {
 		token, _ := r.Context().Value(TokenContextKey).(string)
+		if tokenFromContext, _ := TokenFromContext(r.Context()); tokenFromContext != token {
+			t.Errorf("Expected TokenFromContext to return %q, got %q", token, tokenFromContext)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"message": "Access granted",
@@ -444,8 +447,8 @@ func TestNetHTTPWithCustomTokenExtractor(t *testing.T) {
 			endpoint:       "/api/admin",
 			headerName:     "X-API-Token",
 			headerValue:    "api-key-123",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "token is missing or invalid",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "insufficient permissions",
 		},
 	}
 
@@ -547,6 +550,15 @@ func TestNetHTTPClientDataContext(t *testing.T) {
 	mux.HandleFunc("/profile", gate.ProtectFunc(func(w http.ResponseWriter, r *http.Request) {
 		token, _ := r.Context().Value(TokenContextKey).(string)
 		data, _ := r.Context().Value(DataContextKey).(map[string]interface{})
+		if tokenFromContext, _ := TokenFromContext(r.Context()); tokenFromContext != token {
+			t.Errorf("Expected TokenFromContext to return %q, got %q", token, tokenFromContext)
+		}
+		if dataFromContext, _ := DataFromContext[map[string]interface{}](r.Context()); dataFromContext["username"] != data["username"] {
+			t.Errorf("Expected DataFromContext to return %v, got %v", data, dataFromContext)
+		}
+		if client, _ := ClientFromContext(r.Context()); client == nil || client.Token != token {
+			t.Errorf("Expected ClientFromContext to return the client with the token %q, got %v", token, client)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
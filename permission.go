@@ -0,0 +1,239 @@
+package g8
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// PermissionSeparator is the separator between the segments of a hierarchical permission (e.g. "repo:read").
+	//
+	// A permission implies every permission beneath it, meaning that a client with the permission "admin" also has
+	// the permissions "admin:users" and "admin:users:delete".
+	PermissionSeparator = ":"
+
+	// PermissionWildcard is the wildcard that may be used as the last segment of a permission granted to a client to
+	// grant every permission beneath the preceding segments (e.g. "repo:*" grants "repo:read" and "repo:write"), or
+	// on its own to grant every permission there is.
+	PermissionWildcard = "*"
+)
+
+// permissionImplies checks whether a permission granted to a client implies a permission required by a handler.
+//
+// A granted permission implies a required permission if:
+//   - they are equal (e.g. "repo:read" implies "repo:read")
+//   - the granted permission is PermissionWildcard, which implies every permission
+//   - the granted permission ends with a wildcard segment and the required permission is beneath the preceding
+//     segments (e.g. "repo:*" implies "repo:read" and "repo:issues:write", but not "repo")
+//   - the required permission is beneath the granted permission (e.g. "admin" implies "admin:users")
+func permissionImplies(grantedPermission, requiredPermission string) bool {
+	if grantedPermission == requiredPermission || grantedPermission == PermissionWildcard {
+		return true
+	}
+	if namespace, isWildcard := strings.CutSuffix(grantedPermission, PermissionSeparator+PermissionWildcard); isWildcard {
+		return isBeneathPermission(requiredPermission, namespace)
+	}
+	return isBeneathPermission(requiredPermission, grantedPermission)
+}
+
+// isBeneathPermission checks whether a permission is strictly beneath another permission in the hierarchy
+// (e.g. "admin:users" is beneath "admin", but "administrator" is not)
+func isBeneathPermission(permission, parentPermission string) bool {
+	return len(permission) > len(parentPermission)+len(PermissionSeparator) &&
+		strings.HasPrefix(permission, parentPermission) &&
+		strings.HasPrefix(permission[len(parentPermission):], PermissionSeparator)
+}
+
+// permissionSet is an index of permissions that checks whether any of them implies a required permission by looking up
+// the required permission and each permission above it, rather than going through every permission.
+type permissionSet map[string]struct{}
+
+// newPermissionSet creates a permissionSet containing a slice of permissions
+func newPermissionSet(permissions []string) permissionSet {
+	set := make(permissionSet, len(permissions))
+	set.add(permissions...)
+	return set
+}
+
+// add adds permissions to the set
+func (set permissionSet) add(permissions ...string) {
+	for _, permission := range permissions {
+		set[permission] = struct{}{}
+	}
+}
+
+// implies checks whether any permission of the set implies a required permission (see permissionImplies), which
+// only takes one lookup per segment of the permission required.
+func (set permissionSet) implies(permissionRequired string) bool {
+	if _, exists := set[permissionRequired]; exists {
+		return true
+	}
+	if _, exists := set[PermissionWildcard]; exists {
+		return true
+	}
+	// Look up every permission above the permission required, as well as the wildcard of each of them
+	// (e.g. "repo", "repo:*", "repo:issues" and "repo:issues:*" for "repo:issues:write")
+	for i := strings.Index(permissionRequired, PermissionSeparator); i != -1 && i < len(permissionRequired)-len(PermissionSeparator); {
+		parentPermission := permissionRequired[:i]
+		if _, exists := set[parentPermission]; exists {
+			return true
+		}
+		if _, exists := set[parentPermission+PermissionSeparator+PermissionWildcard]; exists {
+			return true
+		}
+		next := strings.Index(permissionRequired[i+len(PermissionSeparator):], PermissionSeparator)
+		if next == -1 {
+			break
+		}
+		i += len(PermissionSeparator) + next
+	}
+	return false
+}
+
+// permissionTemplateRequirement is a Requirement satisfied by clients that have the permission resulting from the
+// expansion of a permission template (e.g. "org:{org}:write") using the path values of a request.
+//
+// Because it cannot be evaluated without a request, it is never satisfied on its own, and must be expanded using
+// expandPermissionTemplates first.
+type permissionTemplateRequirement struct {
+	template string
+	// parts alternates between literal parts and names of path values, starting with a literal part
+	// (e.g. ["org:", "org", ":write"] for "org:{org}:write")
+	parts []string
+}
+
+// IsSatisfiedBy always returns false, since the template must be expanded using the request's path values first
+func (requirement permissionTemplateRequirement) IsSatisfiedBy(_ *Client) bool {
+	return false
+}
+
+// parsePermissionTemplate parses a permission containing path value names between braces (e.g. "org:{org}:write")
+// into a permissionTemplateRequirement
+func parsePermissionTemplate(template string) (permissionTemplateRequirement, error) {
+	var parts []string
+	remaining := template
+	for {
+		start := strings.IndexAny(remaining, "{}")
+		if start == -1 {
+			parts = append(parts, remaining)
+			break
+		}
+		if remaining[start] == '}' {
+			return permissionTemplateRequirement{}, fmt.Errorf("invalid permission template %q: unexpected \"}\"", template)
+		}
+		end := strings.IndexAny(remaining[start+1:], "{}")
+		if end == -1 || remaining[start+1+end] != '}' {
+			return permissionTemplateRequirement{}, fmt.Errorf("invalid permission template %q: unclosed \"{\"", template)
+		}
+		name := remaining[start+1 : start+1+end]
+		if len(name) == 0 {
+			return permissionTemplateRequirement{}, fmt.Errorf("invalid permission template %q: empty path value name", template)
+		}
+		parts = append(parts, remaining[:start], name)
+		remaining = remaining[start+1+end+1:]
+	}
+	return permissionTemplateRequirement{template: template, parts: parts}, nil
+}
+
+// expand expands the template using the path values of a request.
+//
+// Returns false if one of the path values is empty or contains PermissionSeparator or PermissionWildcard, since such
+// values could otherwise be used to make the permission required refer to another resource.
+func (requirement permissionTemplateRequirement) expand(request *http.Request) (string, bool) {
+	var builder strings.Builder
+	for i, part := range requirement.parts {
+		if i%2 == 0 {
+			builder.WriteString(part)
+			continue
+		}
+		value := request.PathValue(part)
+		if len(value) == 0 || strings.Contains(value, PermissionSeparator) || strings.Contains(value, PermissionWildcard) {
+			return "", false
+		}
+		builder.WriteString(value)
+	}
+	return builder.String(), true
+}
+
+// compilePermissionTemplates replaces every permission of a Requirement that contains path value names between braces
+// by a permissionTemplateRequirement, and returns the resulting Requirement as well as whether it contains any
+// template. It is only used for handlers protected with WithPermissionTemplates.
+//
+// Requirements not provided by g8 are left as is.
+func compilePermissionTemplates(requirement Requirement) (Requirement, bool, error) {
+	switch r := requirement.(type) {
+	case permissionRequirement:
+		if !strings.ContainsAny(string(r), "{}") {
+			return r, false, nil
+		}
+		template, err := parsePermissionTemplate(string(r))
+		if err != nil {
+			return nil, false, err
+		}
+		return template, true, nil
+	case allOfRequirement:
+		requirements, hasTemplates, err := compilePermissionTemplatesOfSlice(r)
+		return allOfRequirement(requirements), hasTemplates, err
+	case anyOfRequirement:
+		requirements, hasTemplates, err := compilePermissionTemplatesOfSlice(r)
+		return anyOfRequirement(requirements), hasTemplates, err
+	case notRequirement:
+		compiledRequirement, hasTemplates, err := compilePermissionTemplates(r.requirement)
+		return notRequirement{requirement: compiledRequirement}, hasTemplates, err
+	default:
+		return requirement, false, nil
+	}
+}
+
+// compilePermissionTemplatesOfSlice does the same thing as compilePermissionTemplates, but for a slice of requirements
+func compilePermissionTemplatesOfSlice(requirements []Requirement) ([]Requirement, bool, error) {
+	compiledRequirements := make([]Requirement, 0, len(requirements))
+	hasTemplates := false
+	for _, requirement := range requirements {
+		compiledRequirement, requirementHasTemplates, err := compilePermissionTemplates(requirement)
+		if err != nil {
+			return nil, false, err
+		}
+		compiledRequirements = append(compiledRequirements, compiledRequirement)
+		hasTemplates = hasTemplates || requirementHasTemplates
+	}
+	return compiledRequirements, hasTemplates, nil
+}
+
+// expandPermissionTemplates replaces every permissionTemplateRequirement of a Requirement compiled by
+// compilePermissionTemplates by the permission resulting from its expansion using the path values of a request.
+//
+// Returns false if one of the templates could not be expanded, in which case the request must not be authorized.
+// Note that leaving the template as is would not be enough, since a template negated by Not would then be satisfied.
+func expandPermissionTemplates(requirement Requirement, request *http.Request) (Requirement, bool) {
+	switch r := requirement.(type) {
+	case permissionTemplateRequirement:
+		permission, ok := r.expand(request)
+		return permissionRequirement(permission), ok
+	case allOfRequirement:
+		requirements, ok := expandPermissionTemplatesOfSlice(r, request)
+		return allOfRequirement(requirements), ok
+	case anyOfRequirement:
+		requirements, ok := expandPermissionTemplatesOfSlice(r, request)
+		return anyOfRequirement(requirements), ok
+	case notRequirement:
+		expandedRequirement, ok := expandPermissionTemplates(r.requirement, request)
+		return notRequirement{requirement: expandedRequirement}, ok
+	default:
+		return requirement, true
+	}
+}
+
+// expandPermissionTemplatesOfSlice does the same thing as expandPermissionTemplates, but for a slice of requirements
+func expandPermissionTemplatesOfSlice(requirements []Requirement, request *http.Request) ([]Requirement, bool) {
+	expandedRequirements := make([]Requirement, 0, len(requirements))
+	for _, requirement := range requirements {
+		expandedRequirement, ok := expandPermissionTemplates(requirement, request)
+		if !ok {
+			return nil, false
+		}
+		expandedRequirements = append(expandedRequirements, expandedRequirement)
+	}
+	return expandedRequirements, true
+}
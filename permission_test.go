@@ -0,0 +1,114 @@
+package g8
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestPermissionImplies(t *testing.T) {
+	scenarios := []struct {
+		grantedPermission  string
+		requiredPermission string
+		expected           bool
+	}{
+		{grantedPermission: "repo:read", requiredPermission: "repo:read", expected: true},
+		{grantedPermission: "repo:read", requiredPermission: "repo:write", expected: false},
+		{grantedPermission: "*", requiredPermission: "repo:read", expected: true},
+		{grantedPermission: "*", requiredPermission: "admin", expected: true},
+		{grantedPermission: "repo:*", requiredPermission: "repo:read", expected: true},
+		{grantedPermission: "repo:*", requiredPermission: "repo:issues:write", expected: true},
+		{grantedPermission: "repo:*", requiredPermission: "repo", expected: false},
+		{grantedPermission: "repo:*", requiredPermission: "repository:read", expected: false},
+		{grantedPermission: "repo:*", requiredPermission: "billing:read", expected: false},
+		{grantedPermission: "repo:issues:*", requiredPermission: "repo:issues:write", expected: true},
+		{grantedPermission: "repo:issues:*", requiredPermission: "repo:read", expected: false},
+		{grantedPermission: "admin", requiredPermission: "admin:users", expected: true},
+		{grantedPermission: "admin", requiredPermission: "admin:users:delete", expected: true},
+		{grantedPermission: "admin", requiredPermission: "administrator", expected: false},
+		{grantedPermission: "admin", requiredPermission: "admin:", expected: false},
+		{grantedPermission: "admin:users", requiredPermission: "admin", expected: false},
+		{grantedPermission: "repo:read", requiredPermission: "repo:*", expected: false},
+		{grantedPermission: "repo:*", requiredPermission: "repo:*", expected: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.grantedPermission+"_"+scenario.requiredPermission, func(t *testing.T) {
+			if implied := permissionImplies(scenario.grantedPermission, scenario.requiredPermission); implied != scenario.expected {
+				t.Errorf("expected permissionImplies(%s, %s) to be %v, got %v", scenario.grantedPermission, scenario.requiredPermission, scenario.expected, implied)
+			}
+		})
+	}
+}
+
+func TestParsePermissionTemplate(t *testing.T) {
+	scenarios := []struct {
+		template      string
+		expectedParts []string
+		expectedError bool
+	}{
+		{template: "org:{org}:write", expectedParts: []string{"org:", "org", ":write"}},
+		{template: "{org}", expectedParts: []string{"", "org", ""}},
+		{template: "org:{org}:repo:{repo}", expectedParts: []string{"org:", "org", ":repo:", "repo", ""}},
+		{template: "org:{org:write", expectedError: true},
+		{template: "org:org}:write", expectedError: true},
+		{template: "org:{}:write", expectedError: true},
+		{template: "org:{{org}}:write", expectedError: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.template, func(t *testing.T) {
+			template, err := parsePermissionTemplate(scenario.template)
+			if scenario.expectedError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !slices.Equal(template.parts, scenario.expectedParts) {
+				t.Errorf("expected parts to be %q, got %q", scenario.expectedParts, template.parts)
+			}
+		})
+	}
+}
+
+func TestExpandPermissionTemplates(t *testing.T) {
+	requirement, hasTemplates, err := compilePermissionTemplates(AllOf(Permission("org:{org}:write"), Not(Permission("org:{org}:suspended")), Permission("read")))
+	if err != nil || !hasTemplates {
+		t.Fatalf("expected requirement to have templates and no error, got (%v, %v)", hasTemplates, err)
+	}
+	scenarios := []struct {
+		org         string
+		permissions []string
+		expanded    bool
+		expected    bool
+	}{
+		{org: "acme", permissions: []string{"org:acme:write", "read"}, expanded: true, expected: true},
+		{org: "acme", permissions: []string{"org:acme:write", "org:globex:suspended", "read"}, expanded: true, expected: true},
+		{org: "acme", permissions: []string{"org:other:write", "read"}, expanded: true, expected: false},
+		{org: "acme", permissions: []string{"org:acme:write", "org:acme:suspended", "read"}, expanded: true, expected: false},
+		{org: "", permissions: []string{"org::write", "read"}, expanded: false},
+		{org: "*", permissions: []string{"org:*:write", "read"}, expanded: false},
+		{org: "acme:write", permissions: []string{"org:acme:write", "read"}, expanded: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.org, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", "/orgs", http.NoBody)
+			request.SetPathValue("org", scenario.org)
+			expandedRequirement, expanded := expandPermissionTemplates(requirement, request)
+			if expanded != scenario.expanded {
+				t.Fatalf("expected expanded to be %v, got %v", scenario.expanded, expanded)
+			}
+			if !expanded {
+				return
+			}
+			if satisfied := expandedRequirement.IsSatisfiedBy(NewClientWithPermissions("token", scenario.permissions)); satisfied != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, satisfied)
+			}
+		})
+	}
+	if _, hasTemplates, _ := compilePermissionTemplates(AnyPermission("admin", "read")); hasTemplates {
+		t.Error("expected requirement without templates to not have templates")
+	}
+}
@@ -0,0 +1,107 @@
+package g8
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProblemDetailsContentType is the content type of the problem details documents (RFC 7807) written by Gate
+	ProblemDetailsContentType = "application/problem+json"
+
+	// DefaultProblemDetailsType is the type of the problem details documents written by Gate, which per RFC 7807,
+	// indicates that the problem has no semantics beyond that of the HTTP status code
+	DefaultProblemDetailsType = "about:blank"
+)
+
+// ProblemDetails is a problem details document, as described by RFC 7807.
+type ProblemDetails struct {
+	// Type is a URI reference identifying the problem type
+	Type string `json:"type"`
+
+	// Title is a short, human-readable summary of the problem type
+	Title string `json:"title"`
+
+	// Status is the HTTP status code of the response
+	Status int `json:"status"`
+
+	// Detail is a human-readable explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is a URI reference identifying this occurrence of the problem
+	Instance string `json:"instance,omitempty"`
+}
+
+// WithProblemDetails makes Gate respond to rejected requests with a problem details document (RFC 7807) if the
+// request accepts application/problem+json or application/json. Requests that accept neither are responded to with the
+// usual plain text bodies.
+//
+// For instance, a request sent with an invalid token and the header Accept: application/problem+json would be
+// rejected with the following body:
+//
+//	{"type":"about:blank","title":"Unauthorized","status":401,"detail":"token is invalid","instance":"/handle"}
+//
+// Since this is implemented as an error handler, it replaces the error handler set through WithErrorHandler, and
+// vice versa.
+func (gate *Gate) WithProblemDetails() *Gate {
+	gate.errorHandler = gate.writeProblemDetails
+	return gate
+}
+
+// writeProblemDetails responds to a request rejected for the given reason with a problem details document if the
+// request accepts it, or with the plain text response body associated with the reason otherwise
+func (gate *Gate) writeProblemDetails(writer http.ResponseWriter, request *http.Request, reason error) {
+	statusCode := gate.statusCode(reason)
+	if !acceptsProblemDetails(request) {
+		writer.WriteHeader(statusCode)
+		_, _ = writer.Write(gate.responseBody(reason))
+		return
+	}
+	body, _ := json.Marshal(ProblemDetails{
+		Type:     DefaultProblemDetailsType,
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   reason.Error(),
+		Instance: request.URL.Path,
+	})
+	writer.Header().Set("Content-Type", ProblemDetailsContentType)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	writer.WriteHeader(statusCode)
+	_, _ = writer.Write(body)
+}
+
+// acceptsProblemDetails checks whether the Accept header of a request explicitly includes
+// application/problem+json or application/json.
+//
+// Wildcards such as */* are ignored, so that clients that don't care about the content type keep getting the plain
+// text response bodies.
+func acceptsProblemDetails(request *http.Request) bool {
+	for _, accept := range request.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, parameters, _ := strings.Cut(mediaRange, ";")
+			mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+			if mediaType != ProblemDetailsContentType && mediaType != "application/json" {
+				continue
+			}
+			if !hasZeroQuality(parameters) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasZeroQuality checks whether the parameters of a media range include a quality value of 0, which means that the
+// media type is not acceptable
+func hasZeroQuality(parameters string) bool {
+	for _, parameter := range strings.Split(parameters, ";") {
+		name, value, _ := strings.Cut(parameter, "=")
+		if strings.EqualFold(strings.TrimSpace(name), "q") {
+			quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return err == nil && quality == 0
+		}
+	}
+	return false
+}
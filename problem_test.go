@@ -0,0 +1,156 @@
+package g8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAcceptsProblemDetails(t *testing.T) {
+	scenarios := []struct {
+		accept   string
+		expected bool
+	}{
+		{accept: "", expected: false},
+		{accept: "*/*", expected: false},
+		{accept: "text/html", expected: false},
+		{accept: "application/problem+json", expected: true},
+		{accept: "application/json", expected: true},
+		{accept: "Application/JSON", expected: true},
+		{accept: "text/html, application/json;q=0.9, */*;q=0.8", expected: true},
+		{accept: "application/json;q=0", expected: false},
+		{accept: "application/json; q=0.0, text/plain", expected: false},
+		{accept: "application/json;charset=utf-8", expected: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.accept, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+			if len(scenario.accept) > 0 {
+				request.Header.Set("Accept", scenario.accept)
+			}
+			if accepts := acceptsProblemDetails(request); accepts != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, accepts)
+			}
+		})
+	}
+}
+
+func TestGate_WithProblemDetails(t *testing.T) {
+	gate := New().
+		WithAuthorizationService(NewAuthorizationService().WithClient(NewClient("token").WithPermission("read"))).
+		WithCustomUnauthorizedResponseBody([]byte("who are you?")).
+		WithProblemDetails()
+	router := http.NewServeMux()
+	router.Handle("/read", gate.ProtectWithPermission(&testHandler{}, "read", WithRouteRateLimiter(NewSlidingWindowRateLimiter(1, time.Minute))))
+	router.Handle("/write", gate.ProtectWithPermission(&testHandler{}, "write"))
+
+	scenarios := []struct {
+		name                   string
+		url                    string
+		token                  string
+		accept                 string
+		expectedResponseCode   int
+		expectedProblemDetails *ProblemDetails
+		expectedResponseBody   string
+	}{
+		{
+			name:                 "invalid-token-without-accept",
+			url:                  "/read",
+			token:                "bad-token",
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: "who are you?",
+		},
+		{
+			name:                   "invalid-token",
+			url:                    "/read",
+			token:                  "bad-token",
+			accept:                 "application/problem+json",
+			expectedResponseCode:   http.StatusUnauthorized,
+			expectedProblemDetails: &ProblemDetails{Type: DefaultProblemDetailsType, Title: "Unauthorized", Status: http.StatusUnauthorized, Detail: ErrInvalidToken.Error(), Instance: "/read"},
+		},
+		{
+			name:                   "insufficient-permissions",
+			url:                    "/write",
+			token:                  "token",
+			accept:                 "application/json",
+			expectedResponseCode:   http.StatusForbidden,
+			expectedProblemDetails: &ProblemDetails{Type: DefaultProblemDetailsType, Title: "Forbidden", Status: http.StatusForbidden, Detail: ErrInsufficientPermissions.Error(), Instance: "/write"},
+		},
+		{
+			name:                 "authorized",
+			url:                  "/read",
+			token:                "token",
+			accept:               "application/problem+json",
+			expectedResponseCode: http.StatusOK,
+		},
+		{
+			name:                   "rate-limited",
+			url:                    "/read",
+			token:                  "token",
+			accept:                 "application/problem+json",
+			expectedResponseCode:   http.StatusTooManyRequests,
+			expectedProblemDetails: &ProblemDetails{Type: DefaultProblemDetailsType, Title: "Too Many Requests", Status: http.StatusTooManyRequests, Detail: ErrTooManyRequests.Error(), Instance: "/read"},
+		},
+		{
+			name:                 "rate-limited-without-accept",
+			url:                  "/read",
+			token:                "token",
+			expectedResponseCode: http.StatusTooManyRequests,
+			expectedResponseBody: DefaultTooManyRequestsResponseBody,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", scenario.url, http.NoBody)
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", scenario.token))
+			if len(scenario.accept) > 0 {
+				request.Header.Set("Accept", scenario.accept)
+			}
+			responseRecorder := httptest.NewRecorder()
+			router.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != scenario.expectedResponseCode {
+				t.Errorf("%s %s should have returned %d, but returned %d instead", request.Method, request.URL, scenario.expectedResponseCode, responseRecorder.Code)
+			}
+			if scenario.expectedProblemDetails == nil {
+				if contentType := responseRecorder.Header().Get("Content-Type"); contentType == ProblemDetailsContentType {
+					t.Errorf("expected Content-Type not to be %s", ProblemDetailsContentType)
+				}
+				if len(scenario.expectedResponseBody) > 0 {
+					if responseBody, _ := io.ReadAll(responseRecorder.Body); string(responseBody) != scenario.expectedResponseBody {
+						t.Errorf("expected response body to be %s, got %s", scenario.expectedResponseBody, string(responseBody))
+					}
+				}
+				return
+			}
+			if contentType := responseRecorder.Header().Get("Content-Type"); contentType != ProblemDetailsContentType {
+				t.Errorf("expected Content-Type to be %s, got %s", ProblemDetailsContentType, contentType)
+			}
+			var problemDetails ProblemDetails
+			if err := json.NewDecoder(responseRecorder.Body).Decode(&problemDetails); err != nil {
+				t.Fatalf("failed to decode problem details: %v", err)
+			}
+			if problemDetails != *scenario.expectedProblemDetails {
+				t.Errorf("expected %+v, got %+v", *scenario.expectedProblemDetails, problemDetails)
+			}
+		})
+	}
+}
+
+func TestGate_WithProblemDetailsWhenTooManyConcurrentRequests(t *testing.T) {
+	gate := New().WithConcurrencyLimitStatusCode(http.StatusServiceUnavailable).WithProblemDetails()
+	request, _ := http.NewRequest("GET", "/handle", http.NoBody)
+	request.Header.Set("Accept", ProblemDetailsContentType)
+	responseRecorder := httptest.NewRecorder()
+	gate.writeProblemDetails(responseRecorder, request, ErrTooManyConcurrentRequests)
+	var problemDetails ProblemDetails
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&problemDetails); err != nil {
+		t.Fatalf("failed to decode problem details: %v", err)
+	}
+	if responseRecorder.Code != http.StatusServiceUnavailable || problemDetails.Status != http.StatusServiceUnavailable || problemDetails.Title != "Service Unavailable" {
+		t.Errorf("expected status %d, got %d and %+v", http.StatusServiceUnavailable, responseRecorder.Code, problemDetails)
+	}
+}
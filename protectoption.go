@@ -0,0 +1,118 @@
+package g8
+
+import "net/http"
+
+// ProtectOption is an option that customizes how a single handler is protected by a Gate.
+//
+// Options are passed to ProtectWithPermissions, ProtectWithPermission, ProtectFuncWithPermissions,
+// ProtectFuncWithPermission or PermissionMiddlewareWithOptions, and only apply to the handler being protected.
+type ProtectOption func(options *protectOptions)
+
+// protectOptions is the configuration resulting from the ProtectOption passed when protecting a handler
+type protectOptions struct {
+	rateLimiter         Limiter
+	costFunc            func(request *http.Request) int
+	permissionTemplates bool
+}
+
+// newProtectOptions applies a slice of ProtectOption and returns the resulting configuration
+func newProtectOptions(options []ProtectOption) *protectOptions {
+	protectOptions := &protectOptions{}
+	for _, option := range options {
+		option(protectOptions)
+	}
+	return protectOptions
+}
+
+// cost returns the number of units of rate limit quota that a request consumes
+func (options *protectOptions) cost(request *http.Request) int {
+	if options.costFunc == nil {
+		return 1
+	}
+	return max(0, options.costFunc(request))
+}
+
+// WithRouteRateLimit adds a rate limit to a single protected handler, on top of any rate limit configured on the
+// Gate itself. The quota is shared by every request going through that handler.
+//
+// Note that the rate limiter is created when WithRouteRateLimit is called, so reusing the same ProtectOption for
+// several handlers will make them share the same quota.
+//
+// If the route rate limit rejects a request, the units the request consumed from the other rate limits (e.g. the
+// client's) are refunded, and vice versa, so that requests that were never served don't count toward any quota.
+//
+// For instance, to cap an expensive endpoint at 1 request per second while other endpoints are capped at 100 requests
+// per second by the Gate's own rate limit:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithRateLimit(100)
+//	router.Handle("/export", gate.ProtectWithPermissions(exportHandler, []string{"export"}, g8.WithRouteRateLimit(1)))
+//	router.Handle("/read", gate.ProtectWithPermissions(readHandler, []string{"read"}))
+func WithRouteRateLimit(maximumRequestsPerSecond int) ProtectOption {
+	return WithRouteRateLimiter(NewRateLimiter(maximumRequestsPerSecond))
+}
+
+// WithRouteRateLimiter does the same thing as WithRouteRateLimit, except that the Limiter passed as parameter is used.
+//
+// Note that the Limiter must not be shared with other handlers unless you want them to share the same quota.
+func WithRouteRateLimiter(limiter Limiter) ProtectOption {
+	return func(options *protectOptions) {
+		options.rateLimiter = limiter
+	}
+}
+
+// WithCost sets the number of units of rate limit quota consumed by each request going through a single protected
+// handler, which defaults to 1. This is useful if some endpoints are much more expensive than others.
+//
+// The cost applies to every rate limit enforced by the Gate for that handler (e.g. WithRateLimit, WithClientRateLimit,
+// WithIPRateLimit and WithRouteRateLimit), but not to quotas and concurrency limits, which always count requests.
+// A cost of 0 means that requests going through the handler do not consume any quota.
+//
+// For instance, to make an export consume 50 units of each client's quota of 100 units per second:
+//
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithClientRateLimit(100)
+//	router.Handle("/export", gate.ProtectWithPermissions(exportHandler, []string{"export"}, g8.WithCost(50)))
+//	router.Handle("/read", gate.ProtectWithPermissions(readHandler, []string{"read"}))
+//
+// Note that a request whose cost is greater than a rate limit will always be rejected by said rate limit. When a
+// request is rejected, its cost is refunded to the rate limits it had already gone through (see RefundableLimiter).
+func WithCost(cost int) ProtectOption {
+	return WithCostFunc(func(_ *http.Request) int {
+		return cost
+	})
+}
+
+// WithCostFunc does the same thing as WithCost, except that the cost of each request is computed by the function
+// passed as parameter, which allows the cost to depend on the request (e.g. on the page size requested).
+//
+// The function is called once per request, before any rate limit is enforced. Negative costs are treated as 0.
+//
+//	g8.WithCostFunc(func(request *http.Request) int {
+//		if request.URL.Query().Get("include") == "all" {
+//			return 10
+//		}
+//		return 1
+//	})
+func WithCostFunc(costFunc func(request *http.Request) int) ProtectOption {
+	return func(options *protectOptions) {
+		options.costFunc = costFunc
+	}
+}
+
+// WithPermissionTemplates makes the permissions required by a single protected handler be interpreted as templates in
+// which the names of path values between braces are replaced by the path values of each request before checking the
+// client's permissions.
+//
+// For instance, to require the permission "org:acme:write" for a request to POST /orgs/acme/projects:
+//
+//	router.Handle("POST /orgs/{org}/projects", gate.ProtectWithPermissions(createProjectHandler, []string{"org:{org}:write"}, g8.WithPermissionTemplates()))
+//
+// Requests for which a path value is empty or contains PermissionSeparator or PermissionWildcard are not authorized,
+// since they could otherwise be used to make the permission required refer to another resource.
+//
+// Without this option, braces have no special meaning and permissions are always interpreted literally.
+// Protecting a handler with this option panics if one of the permissions is not a valid template (e.g. "org:{org").
+func WithPermissionTemplates() ProtectOption {
+	return func(options *protectOptions) {
+		options.permissionTemplates = true
+	}
+}
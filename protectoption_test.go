@@ -0,0 +1,41 @@
+package g8
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewProtectOptions(t *testing.T) {
+	if options := newProtectOptions(nil); options.rateLimiter != nil {
+		t.Error("expected rateLimiter to be nil")
+	}
+	rateLimiter := NewRateLimiter(1)
+	if options := newProtectOptions([]ProtectOption{WithRouteRateLimiter(rateLimiter)}); options.rateLimiter != rateLimiter {
+		t.Error("expected rateLimiter to be set")
+	}
+	if options := newProtectOptions([]ProtectOption{WithRouteRateLimit(5)}); options.rateLimiter == nil {
+		t.Error("expected rateLimiter to be set")
+	}
+}
+
+func TestProtectOptions_Cost(t *testing.T) {
+	request, _ := http.NewRequest("GET", "/handle?include=all", http.NoBody)
+	if cost := newProtectOptions(nil).cost(request); cost != 1 {
+		t.Errorf("expected default cost to be %d, got %d", 1, cost)
+	}
+	if cost := newProtectOptions([]ProtectOption{WithCost(50)}).cost(request); cost != 50 {
+		t.Errorf("expected cost to be %d, got %d", 50, cost)
+	}
+	if cost := newProtectOptions([]ProtectOption{WithCost(-5)}).cost(request); cost != 0 {
+		t.Errorf("expected negative cost to be treated as %d, got %d", 0, cost)
+	}
+	costFunc := func(request *http.Request) int {
+		if request.URL.Query().Get("include") == "all" {
+			return 10
+		}
+		return 1
+	}
+	if cost := newProtectOptions([]ProtectOption{WithCostFunc(costFunc)}).cost(request); cost != 10 {
+		t.Errorf("expected cost to be %d, got %d", 10, cost)
+	}
+}
@@ -0,0 +1,187 @@
+package g8
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	// DefaultQuotaMemoryStoreMaxSize is the maximum number of counters kept by the MemoryStore used by a Quota when no
+	// Store is specified through Quota.WithStore
+	DefaultQuotaMemoryStoreMaxSize = 100000
+
+	// DefaultQuotaKeyPrefix is the prefix of the keys of the counters of a Quota when no prefix is specified through
+	// Quota.WithKeyPrefix
+	DefaultQuotaKeyPrefix = "g8:quota"
+)
+
+// QuotaPeriod is the calendar period over which a Quota's usage is counted
+type QuotaPeriod int
+
+const (
+	// QuotaPeriodDaily resets the usage every day at midnight
+	QuotaPeriodDaily QuotaPeriod = iota
+
+	// QuotaPeriodMonthly resets the usage on the first day of every month at midnight
+	QuotaPeriodMonthly
+)
+
+// String returns the name of the period
+func (period QuotaPeriod) String() string {
+	if period == QuotaPeriodMonthly {
+		return "monthly"
+	}
+	return "daily"
+}
+
+// bounds returns the start and the end of the period that contains the given time in the given location
+func (period QuotaPeriod) bounds(now time.Time, location *time.Location) (start, end time.Time) {
+	now = now.In(location)
+	if period == QuotaPeriodMonthly {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Quota limits the number of requests each client (identified by its token) can make over a calendar period, such as
+// a day or a month.
+//
+// Unlike rate limits, which are meant to protect your service from bursts of requests, quotas are meant to enforce
+// usage plans (e.g. 10000 requests per month).
+//
+// Usage is counted in a Store, which defaults to an in-memory store. If usage must survive restarts or be shared by
+// several instances of your application, use WithStore with a persistent Store.
+type Quota struct {
+	maximumRequests int
+	period          QuotaPeriod
+	location        *time.Location
+	store           Store
+	keyPrefix       string
+}
+
+// QuotaUsage is the usage of a Quota by a client over the current period
+type QuotaUsage struct {
+	// Used is the number of requests made by the client during the current period
+	Used int
+
+	// Limit is the maximum number of requests the client can make during a period
+	Limit int
+
+	// Remaining is the number of requests the client can still make during the current period
+	Remaining int
+
+	// ResetAt is the time at which the current period ends and the usage is reset
+	ResetAt time.Time
+}
+
+// NewQuota creates a Quota that allows each client to make at most maximumRequests over each period.
+//
+// By default, periods are aligned with UTC, and usage is counted in memory.
+//
+//	quota := g8.NewQuota(10000, g8.QuotaPeriodMonthly)
+//	gate := g8.New().WithAuthorizationService(authorizationService).WithQuota(quota)
+func NewQuota(maximumRequests int, period QuotaPeriod) *Quota {
+	return &Quota{
+		maximumRequests: maximumRequests,
+		period:          period,
+		location:        time.UTC,
+		store:           NewMemoryStore(DefaultQuotaMemoryStoreMaxSize),
+		keyPrefix:       DefaultQuotaKeyPrefix,
+	}
+}
+
+// WithLocation sets the location (timezone) used to determine when a period starts and ends.
+//
+// For instance, with time.LoadLocation("America/New_York"), daily quotas are reset at midnight in New York.
+//
+// Panics if location is nil, which is what time.LoadLocation returns alongside an error.
+func (quota *Quota) WithLocation(location *time.Location) *Quota {
+	if location == nil {
+		panic("g8: quota location must not be nil")
+	}
+	quota.location = location
+	return quota
+}
+
+// WithStore sets the Store in which usage is counted.
+//
+// Note that since the MemoryStore used by default evicts the least recently used counters once it is full and does
+// not survive restarts, you should use a persistent Store if quotas are tied to something your clients pay for.
+func (quota *Quota) WithStore(store Store) *Quota {
+	quota.store = store
+	return quota
+}
+
+// WithKeyPrefix sets the prefix of the keys under which usage is counted in the Store, which is DefaultQuotaKeyPrefix
+// by default.
+//
+// Quotas with the same period and key prefix share the same counters, so if several quotas with the same period use
+// the same Store (e.g. a quota per plan backed by the same database), each of them must have a different prefix.
+func (quota *Quota) WithKeyPrefix(keyPrefix string) *Quota {
+	quota.keyPrefix = keyPrefix
+	return quota
+}
+
+// Usage returns the usage of the quota by the client with the given token over the current period.
+//
+// It only reads the usage, so no counter is created for clients that have not made any request yet.
+func (quota *Quota) Usage(token string) (QuotaUsage, error) {
+	key, end := quota.counter(token, time.Now())
+	used, err := quota.store.Get(key)
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	return quota.newUsage(used, end), nil
+}
+
+// counter returns the key of the counter of the client with the given token for the period that contains the given
+// time, as well as the end of said period.
+//
+// The token is hashed so that it is not stored in plain text if the Store is persistent.
+func (quota *Quota) counter(token string, now time.Time) (key string, end time.Time) {
+	start, end := quota.period.bounds(now, quota.location)
+	tokenHash := sha256.Sum256([]byte(token))
+	return quota.keyPrefix + ":" + quota.period.String() + ":" + start.Format("2006-01-02") + ":" + hex.EncodeToString(tokenHash[:]), end
+}
+
+// newUsage creates the QuotaUsage of a period that ends at the given time, given the number of requests made
+func (quota *Quota) newUsage(used int, end time.Time) QuotaUsage {
+	return QuotaUsage{
+		Used:      used,
+		Limit:     quota.maximumRequests,
+		Remaining: max(0, quota.maximumRequests-used),
+		ResetAt:   end,
+	}
+}
+
+// consume increments the usage of the client with the given token over the period that contains the given time if
+// the quota hasn't been exhausted and returns whether the attempt was successful, as well as the resulting usage.
+//
+// If the Store returns an error, the attempt is considered successful.
+func (quota *Quota) consume(token string, now time.Time) (QuotaUsage, bool) {
+	key, end := quota.counter(token, now)
+	used, _, err := quota.store.Increment(key, 1, end.Sub(now))
+	if err != nil {
+		return QuotaUsage{}, true
+	}
+	if used > quota.maximumRequests {
+		// Requests that are rejected must not count toward the usage
+		quota.refund(token, now)
+		return quota.newUsage(used-1, end), false
+	}
+	return quota.newUsage(used, end), true
+}
+
+// refund reverts a successful call to consume made at the given time.
+//
+// The usage is given back to the period the request was counted in, rather than to the current period, so if said
+// period has ended since, the refund is ignored.
+func (quota *Quota) refund(token string, consumedAt time.Time) {
+	key, end := quota.counter(token, consumedAt)
+	if ttl := time.Until(end); ttl > 0 {
+		_, _, _ = quota.store.Increment(key, -1, ttl)
+	}
+}
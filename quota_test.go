@@ -0,0 +1,179 @@
+package g8
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuotaPeriod_Bounds(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database is not available:", err)
+	}
+	scenarios := []struct {
+		name          string
+		period        QuotaPeriod
+		now           time.Time
+		location      *time.Location
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{
+			name:          "daily",
+			period:        QuotaPeriodDaily,
+			now:           time.Date(2024, 2, 29, 13, 37, 0, 0, time.UTC),
+			location:      time.UTC,
+			expectedStart: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "daily-with-location",
+			period:        QuotaPeriodDaily,
+			now:           time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC), // 2024-02-29 21:00 in New York
+			location:      newYork,
+			expectedStart: time.Date(2024, 2, 29, 0, 0, 0, 0, newYork),
+			expectedEnd:   time.Date(2024, 3, 1, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:          "monthly",
+			period:        QuotaPeriodMonthly,
+			now:           time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+			location:      time.UTC,
+			expectedStart: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "monthly-with-location",
+			period:        QuotaPeriodMonthly,
+			now:           time.Date(2024, 11, 1, 3, 0, 0, 0, time.UTC), // 2024-10-31 23:00 in New York
+			location:      newYork,
+			expectedStart: time.Date(2024, 10, 1, 0, 0, 0, 0, newYork),
+			expectedEnd:   time.Date(2024, 11, 1, 0, 0, 0, 0, newYork),
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			start, end := scenario.period.bounds(scenario.now, scenario.location)
+			if !start.Equal(scenario.expectedStart) {
+				t.Errorf("expected start to be %s, got %s", scenario.expectedStart, start)
+			}
+			if !end.Equal(scenario.expectedEnd) {
+				t.Errorf("expected end to be %s, got %s", scenario.expectedEnd, end)
+			}
+		})
+	}
+}
+
+func TestQuota_Usage(t *testing.T) {
+	quota := NewQuota(2, QuotaPeriodDaily)
+	if usage, err := quota.Usage("token"); err != nil || usage.Used != 0 || usage.Remaining != 2 || usage.Limit != 2 {
+		t.Errorf("unexpected usage %+v (err=%v)", usage, err)
+	}
+	if _, ok := quota.consume("token", time.Now()); !ok {
+		t.Error("expected quota to not be exhausted")
+	}
+	if _, ok := quota.consume("token", time.Now()); !ok {
+		t.Error("expected quota to not be exhausted")
+	}
+	if usage, ok := quota.consume("token", time.Now()); ok || usage.Used != 2 || usage.Remaining != 0 {
+		t.Errorf("expected quota to be exhausted, got %+v", usage)
+	}
+	// The rejected attempt must not count toward the usage
+	usage, err := quota.Usage("token")
+	if err != nil || usage.Used != 2 || usage.Remaining != 0 {
+		t.Errorf("unexpected usage %+v (err=%v)", usage, err)
+	}
+	if _, expectedResetAt := QuotaPeriodDaily.bounds(time.Now(), time.UTC); !usage.ResetAt.Equal(expectedResetAt) {
+		t.Errorf("expected ResetAt to be %s, got %s", expectedResetAt, usage.ResetAt)
+	}
+	if usage, _ := quota.Usage("other-token"); usage.Used != 0 {
+		t.Errorf("expected other-token to have its own usage, got %+v", usage)
+	}
+}
+
+func TestQuota_UsageDoesNotCreateCounter(t *testing.T) {
+	store := NewFakeStore()
+	quota := NewQuota(10, QuotaPeriodDaily).WithStore(store)
+	if usage, err := quota.Usage("token"); err != nil || usage.Used != 0 || usage.Remaining != 10 {
+		t.Errorf("unexpected usage %+v (err=%v)", usage, err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("expected reading the usage to not create any counter, got %d counters", store.Len())
+	}
+}
+
+func TestQuota_RefundAfterPeriodEnded(t *testing.T) {
+	store := NewFakeStore()
+	quota := NewQuota(10, QuotaPeriodDaily).WithStore(store)
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	quota.consume("token", yesterday)
+	quota.consume("token", now)
+	// The request was counted in the previous period, so the refund must not be given back to the current period
+	quota.refund("token", yesterday)
+	previousKey, _ := quota.counter("token", yesterday)
+	currentKey, _ := quota.counter("token", now)
+	if store.Count(previousKey) != 1 || store.Count(currentKey) != 1 {
+		t.Errorf("expected the refund to be ignored, got %d in the previous period and %d in the current one", store.Count(previousKey), store.Count(currentKey))
+	}
+	quota.refund("token", now)
+	if store.Count(currentKey) != 0 {
+		t.Errorf("expected the refund to be given back to the current period, got %d", store.Count(currentKey))
+	}
+}
+
+func TestQuota_WithStore(t *testing.T) {
+	store := NewFakeStore()
+	quota := NewQuota(10, QuotaPeriodMonthly).WithStore(store)
+	quota.consume("token", time.Now())
+	if store.Len() != 1 {
+		t.Fatalf("expected the store to have 1 counter, got %d", store.Len())
+	}
+	// A quota using the same store, such as one created after a restart, should see the same usage
+	if usage, _ := NewQuota(10, QuotaPeriodMonthly).WithStore(store).Usage("token"); usage.Used != 1 {
+		t.Errorf("expected usage to be shared through the store, got %+v", usage)
+	}
+	store.WithError(errors.New("store unavailable"))
+	if _, ok := quota.consume("token", time.Now()); !ok {
+		t.Error("expected quota to fail open when the store returns an error")
+	}
+	if _, err := quota.Usage("token"); err == nil {
+		t.Error("expected Usage to return the store's error")
+	}
+}
+
+func TestQuota_WithLocationNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithLocation to panic")
+		}
+	}()
+	NewQuota(10, QuotaPeriodDaily).WithLocation(nil)
+}
+
+func TestQuota_CounterDoesNotContainToken(t *testing.T) {
+	key, _ := NewQuota(10, QuotaPeriodDaily).counter("secret-token", time.Now())
+	if strings.Contains(key, "secret-token") {
+		t.Errorf("expected the key to not contain the token, got %s", key)
+	}
+	if !strings.HasPrefix(key, DefaultQuotaKeyPrefix+":daily:") {
+		t.Errorf("expected the key to start with %s, got %s", DefaultQuotaKeyPrefix+":daily:", key)
+	}
+}
+
+func TestQuota_WithKeyPrefix(t *testing.T) {
+	store := NewFakeStore()
+	freeQuota := NewQuota(10, QuotaPeriodMonthly).WithStore(store).WithKeyPrefix("free")
+	proQuota := NewQuota(10, QuotaPeriodMonthly).WithStore(store).WithKeyPrefix("pro")
+	freeQuota.consume("token", time.Now())
+	proQuota.consume("token", time.Now())
+	// Quotas with different prefixes must not share counters, even if they have the same period and store
+	if store.Len() != 2 {
+		t.Errorf("expected the store to have 2 counters, got %d", store.Len())
+	}
+	if usage, _ := freeQuota.Usage("token"); usage.Used != 1 {
+		t.Errorf("expected usage to be %d, got %d", 1, usage.Used)
+	}
+}
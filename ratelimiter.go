@@ -1,6 +1,7 @@
 package g8
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -10,7 +11,10 @@ type RateLimiter struct {
 	maximumExecutionsPerSecond int
 	executionsLeftInWindow     int
 	windowStartTime            time.Time
-	mutex                      sync.Mutex
+	// window is the number of windows that have started since the creation of the rate limiter, which is used to
+	// ignore refunds of units consumed from a previous window
+	window int64
+	mutex  sync.Mutex
 }
 
 // NewRateLimiter creates a RateLimiter
@@ -28,15 +32,78 @@ func NewRateLimiter(maximumExecutionsPerSecond int) *RateLimiter {
 // Returns false if the execution was not successful (rate limit quota has been reached)
 // Returns true if the execution was successful (rate limit quota has not been reached)
 func (r *RateLimiter) Try() bool {
+	return r.TryN(1)
+}
+
+// TryN does the same thing as Try, but consumes n units of the rate limit quota instead of 1, which is useful if some
+// executions are more expensive than others.
+//
+// The attempt either consumes all n units or none of them.
+func (r *RateLimiter) TryN(n int) bool {
+	return r.TakeN(n).Allowed
+}
+
+// Take does the same thing as Try, but returns a RateLimitResult instead of a bool, which includes the number of
+// executions left in the current window and the time until the next window starts.
+func (r *RateLimiter) Take() RateLimitResult {
+	return r.TakeN(1)
+}
+
+// TakeN does the same thing as TryN, but returns a RateLimitResult instead of a bool.
+//
+// A negative n is treated as 0.
+func (r *RateLimiter) TakeN(n int) RateLimitResult {
+	n = max(0, n)
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	if time.Now().Add(-time.Second).After(r.windowStartTime) {
-		r.windowStartTime = time.Now()
+	now := time.Now()
+	if now.Add(-time.Second).After(r.windowStartTime) {
+		r.windowStartTime = now
 		r.executionsLeftInWindow = r.maximumExecutionsPerSecond
+		r.window++
+	}
+	result := RateLimitResult{
+		Limit:      r.maximumExecutionsPerSecond,
+		ResetAfter: r.windowStartTime.Add(time.Second).Sub(now),
+		Window:     r.window,
 	}
-	if r.executionsLeftInWindow == 0 {
-		return false
+	if r.executionsLeftInWindow < n {
+		result.Remaining = r.executionsLeftInWindow
+		result.RetryAfter = result.ResetAfter
+		return result
 	}
-	r.executionsLeftInWindow--
-	return true
+	r.executionsLeftInWindow -= n
+	result.Allowed = true
+	result.Remaining = r.executionsLeftInWindow
+	return result
+}
+
+// RefundN gives back n units of the rate limit quota consumed by a successful call to TakeN, without exceeding the
+// maximum number of executions per second.
+//
+// The refund is ignored if the units were consumed from a window other than the current one.
+func (r *RateLimiter) RefundN(n int, window int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if window != r.window {
+		return
+	}
+	r.executionsLeftInWindow = min(r.maximumExecutionsPerSecond, r.executionsLeftInWindow+n)
+}
+
+// Wait blocks until the rate limit quota is available and consumes it, or until the context is done, whichever comes
+// first.
+//
+// Returns nil if the execution was successful, ctx.Err() if the context was done before the quota was available,
+// or ErrWaitExceedsDeadline if the quota will not be available before the context's deadline.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN does the same thing as Wait, but waits for n units of the rate limit quota instead of 1.
+//
+// Returns ErrCostExceedsLimit right away if n is greater than the rate limit quota.
+func (r *RateLimiter) WaitN(ctx context.Context, n int) error {
+	_, err := waitForLimiter(ctx, r, n)
+	return err
 }
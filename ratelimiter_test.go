@@ -1,6 +1,8 @@
 package g8
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -71,3 +73,120 @@ func TestRateLimiter_TryAlwaysUnderRateLimit(t *testing.T) {
 		time.Sleep(51 * time.Millisecond)
 	}
 }
+
+func TestRateLimiter_Take(t *testing.T) {
+	rl := NewRateLimiter(2)
+	result := rl.Take()
+	if !result.Allowed || result.Limit != 2 || result.Remaining != 1 || result.RetryAfter != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.ResetAfter <= 0 || result.ResetAfter > time.Second {
+		t.Errorf("expected ResetAfter to be within the current window, got %s", result.ResetAfter)
+	}
+	result = rl.Take()
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	result = rl.Take()
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.RetryAfter != result.ResetAfter {
+		t.Errorf("expected RetryAfter to be equal to ResetAfter, got %s and %s", result.RetryAfter, result.ResetAfter)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	rl := NewRateLimiter(1)
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	start := time.Now()
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("expected Wait to block until the next window, but it only blocked for %s", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitWithDeadlineTooShort(t *testing.T) {
+	rl := NewRateLimiter(1)
+	rl.Try()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := rl.Wait(ctx); !errors.Is(err, ErrWaitExceedsDeadline) {
+		t.Errorf("expected %v, got %v", ErrWaitExceedsDeadline, err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("expected Wait to return right away, but it blocked for %s", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitWithCanceledContext(t *testing.T) {
+	rl := NewRateLimiter(1)
+	rl.Try()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := rl.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestRateLimiter_TryN(t *testing.T) {
+	rl := NewRateLimiter(10)
+	if !rl.TryN(7) {
+		t.Error("expected to not be rate limited")
+	}
+	if rl.TryN(4) {
+		t.Error("expected to be rate limited, since only 3 executions are left")
+	}
+	// The rejected attempt must not have consumed any of the quota
+	if result := rl.TakeN(3); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if !rl.TryN(0) {
+		t.Error("expected an attempt with a cost of 0 to not be rate limited")
+	}
+}
+
+func TestRateLimiter_WaitNWithCostExceedingLimit(t *testing.T) {
+	rl := NewRateLimiter(5)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := rl.WaitN(ctx, 6); !errors.Is(err, ErrCostExceedsLimit) {
+		t.Errorf("expected %v, got %v", ErrCostExceedsLimit, err)
+	}
+}
+
+func TestRateLimiter_RefundN(t *testing.T) {
+	rl := NewRateLimiter(3)
+	result := rl.TakeN(3)
+	rl.RefundN(2, result.Window)
+	if !rl.TryN(2) {
+		t.Error("expected the refunded units to be available")
+	}
+	rl.RefundN(10, result.Window)
+	if rl.executionsLeftInWindow != 3 {
+		t.Errorf("expected refund to not exceed the limit, got %d executions left", rl.executionsLeftInWindow)
+	}
+}
+
+func TestRateLimiter_RefundNAfterWindowReset(t *testing.T) {
+	rl := NewRateLimiter(2)
+	result := rl.TakeN(1)
+	// Make the current window end
+	rl.windowStartTime = rl.windowStartTime.Add(-2 * time.Second)
+	if !rl.TryN(2) {
+		t.Fatal("expected the quota of the new window to be available")
+	}
+	// The unit was consumed from the previous window, so refunding it must not allow a third execution in the new one
+	rl.RefundN(1, result.Window)
+	if rl.Try() {
+		t.Error("expected the refund of a unit consumed from the previous window to be ignored")
+	}
+}
@@ -0,0 +1,66 @@
+package g8
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TwiN/gocache/v2"
+)
+
+const (
+	// DefaultRateLimiterPoolMaxSize is the default maximum number of rate limiters kept in memory by a Gate that rate
+	// limits requests by key (e.g. by token).
+	// Once that number is reached, the least recently used rate limiter is evicted.
+	DefaultRateLimiterPoolMaxSize = 100000
+
+	// DefaultRateLimiterPoolIdleTimeout is the default duration after which a rate limiter that hasn't been used is
+	// evicted from memory.
+	DefaultRateLimiterPoolIdleTimeout = 10 * time.Minute
+)
+
+// rateLimiterPool is a bounded collection of rate limiters, each associated with a key and created on demand through
+// newLimiterFunc.
+//
+// Rate limiters that haven't been used for longer than the idle timeout are evicted, and if the maximum size of the
+// pool is reached, the least recently used rate limiter is evicted.
+type rateLimiterPool struct {
+	newLimiterFunc func(key string) Limiter
+	idleTimeout    time.Duration
+
+	cache *gocache.Cache
+	mutex sync.Mutex
+}
+
+// newRateLimiterPool creates a rateLimiterPool
+func newRateLimiterPool(newLimiterFunc func(key string) Limiter, maxSize int, idleTimeout time.Duration) *rateLimiterPool {
+	return &rateLimiterPool{
+		newLimiterFunc: newLimiterFunc,
+		idleTimeout:    idleTimeout,
+		cache:          gocache.NewCache().WithEvictionPolicy(gocache.LeastRecentlyUsed).WithMaxSize(maxSize).WithDefaultTTL(idleTimeout),
+	}
+}
+
+// get retrieves the rate limiter associated with a key, creating it with the pool's newLimiterFunc if it doesn't exist
+// yet.
+func (pool *rateLimiterPool) get(key string) Limiter {
+	return pool.getOrCreate(key, func() Limiter {
+		return pool.newLimiterFunc(key)
+	})
+}
+
+// getOrCreate retrieves the rate limiter associated with a key, creating it with the newLimiterFunc passed as
+// parameter if it doesn't exist yet.
+func (pool *rateLimiterPool) getOrCreate(key string, newLimiterFunc func() Limiter) Limiter {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if value, exists := pool.cache.Get(key); exists {
+		if limiter, ok := value.(Limiter); ok {
+			// Push back the expiration, since the rate limiter is not idle
+			pool.cache.Expire(key, pool.idleTimeout)
+			return limiter
+		}
+	}
+	limiter := newLimiterFunc()
+	pool.cache.Set(key, limiter)
+	return limiter
+}
@@ -0,0 +1,61 @@
+package g8
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestRateLimiterFunc(maximumExecutionsPerSecond int) func(key string) Limiter {
+	return func(_ string) Limiter {
+		return NewRateLimiter(maximumExecutionsPerSecond)
+	}
+}
+
+func TestRateLimiterPool_Get(t *testing.T) {
+	pool := newRateLimiterPool(newTestRateLimiterFunc(2), 10, time.Minute)
+	for i := 0; i < 2; i++ {
+		if !pool.get("a").TakeN(1).Allowed {
+			t.Fatal("expected a to not be rate limited")
+		}
+	}
+	if pool.get("a").TakeN(1).Allowed {
+		t.Error("expected a to be rate limited")
+	}
+	// Every key should have its own rate limiter
+	if !pool.get("b").TakeN(1).Allowed {
+		t.Error("expected b to not be rate limited")
+	}
+}
+
+func TestRateLimiterPool_MaxSize(t *testing.T) {
+	pool := newRateLimiterPool(newTestRateLimiterFunc(1), 2, time.Minute)
+	pool.get("a").TakeN(1)
+	pool.get("b").TakeN(1)
+	pool.get("c").TakeN(1)
+	if count := pool.cache.Count(); count != 2 {
+		t.Errorf("expected pool to have %d rate limiters, got %d", 2, count)
+	}
+	// Since "a" was the least recently used rate limiter, it should've been evicted and a new rate limiter with a
+	// fresh quota should be created
+	if !pool.get("a").TakeN(1).Allowed {
+		t.Error("expected a to not be rate limited, because its rate limiter should've been evicted")
+	}
+}
+
+func TestRateLimiterPool_IdleTimeout(t *testing.T) {
+	pool := newRateLimiterPool(newTestRateLimiterFunc(1), 10, 50*time.Millisecond)
+	rateLimiter := pool.get("a")
+	time.Sleep(30 * time.Millisecond)
+	if pool.get("a") != rateLimiter {
+		t.Error("expected the same rate limiter to be returned, since it wasn't idle for long enough")
+	}
+	time.Sleep(30 * time.Millisecond)
+	// The previous access should've pushed back the expiration
+	if pool.get("a") != rateLimiter {
+		t.Error("expected the same rate limiter to be returned, since its expiration should've been pushed back")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if pool.get("a") == rateLimiter {
+		t.Error("expected a new rate limiter to be returned, since the previous one was idle for too long")
+	}
+}
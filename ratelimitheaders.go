@@ -0,0 +1,103 @@
+package g8
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// RateLimitLimitHeader is the header in which Gate writes the maximum number of requests allowed by the quota
+	RateLimitLimitHeader = "RateLimit-Limit"
+
+	// RateLimitRemainingHeader is the header in which Gate writes the number of requests left in the quota
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+
+	// RateLimitResetHeader is the header in which Gate writes the number of seconds until the quota is reset
+	RateLimitResetHeader = "RateLimit-Reset"
+
+	// RetryAfterHeader is the header in which Gate writes the number of seconds to wait before retrying a request that
+	// was rejected because the quota was exceeded
+	RetryAfterHeader = "Retry-After"
+)
+
+// rateLimitTracker keeps track of the most restrictive RateLimitResult among every Limiter a request went through, as
+// well as of the limiters whose quota was consumed by the request, so that it can be refunded if the request is rejected
+type rateLimitTracker struct {
+	result  RateLimitResult
+	tracked bool
+
+	consumedLimiters []consumedLimiter
+	consumedCost     int
+
+	// waitDeadline is the time until which the request may wait for the quota of the limiters to be available, if a
+	// maximum wait was configured through WithRateLimitMaxWait
+	waitDeadline time.Time
+}
+
+// track records a RateLimitResult if it is more restrictive than the one previously recorded and returns whether the
+// attempt that led to said result was successful
+func (tracker *rateLimitTracker) track(result RateLimitResult) bool {
+	if !tracker.tracked || result.isMoreRestrictiveThan(tracker.result) {
+		tracker.result = result
+		tracker.tracked = true
+	}
+	return result.Allowed
+}
+
+// consumedLimiter is a Limiter whose quota was consumed by a request, along with the RateLimitResult of said
+// consumption, which includes the window the units were consumed from (see RateLimitResult.Window)
+type consumedLimiter struct {
+	limiter Limiter
+	result  RateLimitResult
+}
+
+// consume records that cost units of a Limiter's quota were consumed by the request, as reported by result
+func (tracker *rateLimitTracker) consume(limiter Limiter, result RateLimitResult, cost int) {
+	tracker.consumedLimiters = append(tracker.consumedLimiters, consumedLimiter{limiter: limiter, result: result})
+	tracker.consumedCost = cost
+}
+
+// refund gives back the units consumed by the request to every Limiter that implements RefundableLimiter.
+//
+// If none of the limiters rejected the request, every result recorded is the result of a consumption, so the result
+// reported by writeHeaders is recomputed to account for the units given back.
+func (tracker *rateLimitTracker) refund() {
+	recomputeResult := tracker.tracked && tracker.result.Allowed && len(tracker.consumedLimiters) > 0
+	if recomputeResult {
+		tracker.tracked = false
+	}
+	for _, consumed := range tracker.consumedLimiters {
+		result := consumed.result
+		if refundableLimiter, ok := consumed.limiter.(RefundableLimiter); ok {
+			refundableLimiter.RefundN(tracker.consumedCost, result.Window)
+			result.Remaining = min(result.Limit, result.Remaining+tracker.consumedCost)
+		}
+		if recomputeResult {
+			tracker.track(result)
+		}
+	}
+	tracker.consumedLimiters = nil
+}
+
+// writeHeaders writes the rate limit headers based on the most restrictive RateLimitResult recorded, if any.
+//
+// Retry-After is only written if the request was rejected.
+func (tracker *rateLimitTracker) writeHeaders(writer http.ResponseWriter) {
+	if !tracker.tracked {
+		return
+	}
+	writer.Header().Set(RateLimitLimitHeader, strconv.Itoa(tracker.result.Limit))
+	writer.Header().Set(RateLimitRemainingHeader, strconv.Itoa(tracker.result.Remaining))
+	writer.Header().Set(RateLimitResetHeader, strconv.Itoa(durationToSeconds(tracker.result.ResetAfter)))
+	if !tracker.result.Allowed {
+		// A client retrying immediately would just be rejected again, so we never return less than 1 second
+		writer.Header().Set(RetryAfterHeader, strconv.Itoa(max(1, durationToSeconds(tracker.result.RetryAfter))))
+	}
+}
+
+// durationToSeconds converts a duration to a number of seconds, rounded up
+func durationToSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,101 @@
+package g8
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTracker_TrackKeepsMostRestrictiveResult(t *testing.T) {
+	var tracker rateLimitTracker
+	tracker.track(RateLimitResult{Allowed: true, Limit: 100, Remaining: 50})
+	tracker.track(RateLimitResult{Allowed: true, Limit: 10, Remaining: 5})
+	tracker.track(RateLimitResult{Allowed: true, Limit: 1000, Remaining: 999})
+	if tracker.result.Limit != 10 {
+		t.Errorf("expected the result with the least remaining executions to be kept, got %+v", tracker.result)
+	}
+	tracker.track(RateLimitResult{Allowed: false, Limit: 1000, RetryAfter: time.Second})
+	if tracker.result.Allowed || tracker.result.Limit != 1000 {
+		t.Errorf("expected the rejected result to be kept, got %+v", tracker.result)
+	}
+	tracker.track(RateLimitResult{Allowed: true, Limit: 1, Remaining: 0})
+	if tracker.result.Allowed {
+		t.Errorf("expected the rejected result to be kept, got %+v", tracker.result)
+	}
+}
+
+func TestRateLimitTracker_WriteHeaders(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	var tracker rateLimitTracker
+	tracker.writeHeaders(responseRecorder)
+	if len(responseRecorder.Header()) != 0 {
+		t.Errorf("expected no headers to be written, got %v", responseRecorder.Header())
+	}
+	tracker.track(RateLimitResult{Allowed: false, Limit: 10, Remaining: 0, ResetAfter: 1500 * time.Millisecond, RetryAfter: 100 * time.Millisecond})
+	tracker.writeHeaders(responseRecorder)
+	for header, expectedValue := range map[string]string{
+		RateLimitLimitHeader:     "10",
+		RateLimitRemainingHeader: "0",
+		RateLimitResetHeader:     "2",
+		RetryAfterHeader:         "1",
+	} {
+		if value := responseRecorder.Header().Get(header); value != expectedValue {
+			t.Errorf("expected header %s to be %s, got %s", header, expectedValue, value)
+		}
+	}
+}
+
+func TestRateLimitTracker_Refund(t *testing.T) {
+	var tracker rateLimitTracker
+	refundableLimiter, otherLimiter := NewRateLimiter(5), &fixedLimiter{}
+	tracker.consume(refundableLimiter, refundableLimiter.TakeN(2), 2)
+	tracker.consume(otherLimiter, otherLimiter.TakeN(2), 2)
+	tracker.refund()
+	if refundableLimiter.executionsLeftInWindow != 5 {
+		t.Errorf("expected %d executions left, got %d", 5, refundableLimiter.executionsLeftInWindow)
+	}
+	// Refunding twice must not give back the units twice
+	refundableLimiter.TakeN(1)
+	tracker.refund()
+	if refundableLimiter.executionsLeftInWindow != 4 {
+		t.Errorf("expected %d executions left, got %d", 4, refundableLimiter.executionsLeftInWindow)
+	}
+}
+
+func TestRateLimitTracker_RefundUpdatesResult(t *testing.T) {
+	var tracker rateLimitTracker
+	gateLimiter, routeLimiter, otherLimiter := NewRateLimiter(5), NewRateLimiter(3), &fixedLimiter{}
+	for _, limiter := range []Limiter{gateLimiter, routeLimiter, otherLimiter} {
+		result := limiter.TakeN(1)
+		tracker.consume(limiter, result, 1)
+		tracker.track(result)
+	}
+	tracker.refund()
+	// The units given back must be reflected in the result reported, and a limiter that was not refunded, whose result
+	// is now the most restrictive one, must be reported instead
+	if !tracker.result.Allowed || tracker.result.Remaining != 0 {
+		t.Errorf("expected the result of the limiter that was not refunded to be reported, got %+v", tracker.result)
+	}
+	tracker = rateLimitTracker{}
+	for _, limiter := range []Limiter{gateLimiter, routeLimiter} {
+		result := limiter.TakeN(1)
+		tracker.consume(limiter, result, 1)
+		tracker.track(result)
+	}
+	tracker.refund()
+	if tracker.result.Limit != 3 || tracker.result.Remaining != 3 {
+		t.Errorf("expected the result to report %d remaining out of %d, got %+v", 3, 3, tracker.result)
+	}
+	// Refunding again must not lose the result
+	tracker.refund()
+	if !tracker.tracked || tracker.result.Remaining != 3 {
+		t.Errorf("expected the result to have been kept, got %+v", tracker.result)
+	}
+}
+
+// fixedLimiter is a Limiter that always allows attempts and does not implement RefundableLimiter
+type fixedLimiter struct{}
+
+func (limiter *fixedLimiter) TakeN(n int) RateLimitResult {
+	return RateLimitResult{Allowed: true}
+}
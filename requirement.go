@@ -0,0 +1,148 @@
+package g8
+
+// Requirement is a condition that a client must satisfy to be authorized.
+//
+// Requirements can be combined to express more complex conditions than the "all of these permissions" semantics of
+// Gate.ProtectWithPermissions. For instance, to require either the permission "admin", or the permission "support"
+// without the permission "intern":
+//
+//	requirement := g8.AnyOf(g8.Permission("admin"), g8.AllOf(g8.Permission("support"), g8.Not(g8.Permission("intern"))))
+type Requirement interface {
+	// IsSatisfiedBy checks whether a client satisfies the requirement
+	IsSatisfiedBy(client *Client) bool
+}
+
+// permissionRequirement is a Requirement satisfied by clients that have a given permission
+type permissionRequirement string
+
+// allOfRequirement is a Requirement satisfied by clients that satisfy every one of its requirements
+type allOfRequirement []Requirement
+
+// anyOfRequirement is a Requirement satisfied by clients that satisfy at least one of its requirements
+type anyOfRequirement []Requirement
+
+// notRequirement is a Requirement satisfied by clients that do not satisfy its requirement
+type notRequirement struct {
+	requirement Requirement
+}
+
+// Permission creates a Requirement satisfied by clients that have the given permission
+func Permission(permission string) Requirement {
+	return permissionRequirement(permission)
+}
+
+// AllOf creates a Requirement satisfied by clients that satisfy every one of the requirements passed as parameter.
+//
+// If no requirements are passed, every client satisfies the resulting Requirement.
+func AllOf(requirements ...Requirement) Requirement {
+	return allOfRequirement(requirements)
+}
+
+// AnyOf creates a Requirement satisfied by clients that satisfy at least one of the requirements passed as parameter.
+//
+// If no requirements are passed, no client satisfies the resulting Requirement.
+func AnyOf(requirements ...Requirement) Requirement {
+	return anyOfRequirement(requirements)
+}
+
+// Not creates a Requirement satisfied by clients that do not satisfy the requirement passed as parameter.
+//
+// Permissions negated by Not are only matched against the permissions explicitly granted to a client, whether directly
+// or through its roles, rather than against the permissions they imply. This way, a client with PermissionWildcard or
+// with "admin" still satisfies Not(Permission("suspended")) or Not(Permission("admin:readonly")), unless it has been
+// explicitly granted said permission.
+func Not(requirement Requirement) Requirement {
+	return notRequirement{requirement: requirement}
+}
+
+// AllPermissions creates a Requirement satisfied by clients that have every one of the permissions passed as
+// parameter, which is the Requirement used by Gate.ProtectWithPermissions.
+//
+// Equivalent to using AllOf with a Permission for each permission
+func AllPermissions(permissions ...string) Requirement {
+	requirements := make(allOfRequirement, 0, len(permissions))
+	for _, permission := range permissions {
+		requirements = append(requirements, permissionRequirement(permission))
+	}
+	return requirements
+}
+
+// AnyPermission creates a Requirement satisfied by clients that have at least one of the permissions passed as
+// parameter, which is the Requirement used by Gate.ProtectWithAnyPermission.
+//
+// Equivalent to using AnyOf with a Permission for each permission
+func AnyPermission(permissions ...string) Requirement {
+	requirements := make(anyOfRequirement, 0, len(permissions))
+	for _, permission := range permissions {
+		requirements = append(requirements, permissionRequirement(permission))
+	}
+	return requirements
+}
+
+// IsSatisfiedBy checks whether a client has the permission
+func (requirement permissionRequirement) IsSatisfiedBy(client *Client) bool {
+	return client.HasPermission(string(requirement))
+}
+
+// IsSatisfiedBy checks whether a client satisfies every requirement
+func (requirement allOfRequirement) IsSatisfiedBy(client *Client) bool {
+	for _, r := range requirement {
+		if !r.IsSatisfiedBy(client) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSatisfiedBy checks whether a client satisfies at least one requirement
+func (requirement anyOfRequirement) IsSatisfiedBy(client *Client) bool {
+	for _, r := range requirement {
+		if r.IsSatisfiedBy(client) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSatisfiedBy checks whether a client does not explicitly satisfy the requirement (see Not)
+func (requirement notRequirement) IsSatisfiedBy(client *Client) bool {
+	return !isExplicitlySatisfiedBy(requirement.requirement, client)
+}
+
+// isExplicitlySatisfiedBy checks whether a client satisfies a requirement using only the permissions explicitly
+// granted to the client, which is how the requirements negated by Not are evaluated.
+//
+// A requirement negated twice is no longer negated, so it is evaluated normally again. Requirements that are not
+// provided by g8 are evaluated normally as well.
+func isExplicitlySatisfiedBy(requirement Requirement, client *Client) bool {
+	switch r := requirement.(type) {
+	case permissionRequirement:
+		return client.hasExplicitPermission(string(r))
+	case allOfRequirement:
+		for _, subRequirement := range r {
+			if !isExplicitlySatisfiedBy(subRequirement, client) {
+				return false
+			}
+		}
+		return true
+	case anyOfRequirement:
+		for _, subRequirement := range r {
+			if isExplicitlySatisfiedBy(subRequirement, client) {
+				return true
+			}
+		}
+		return false
+	case notRequirement:
+		return !r.requirement.IsSatisfiedBy(client)
+	default:
+		return requirement.IsSatisfiedBy(client)
+	}
+}
+
+// Make sure that the requirements provided by g8 are compatible with the interface
+var (
+	_ Requirement = permissionRequirement("")
+	_ Requirement = allOfRequirement(nil)
+	_ Requirement = anyOfRequirement(nil)
+	_ Requirement = notRequirement{}
+)
@@ -0,0 +1,67 @@
+package g8
+
+import (
+	"testing"
+)
+
+func TestRequirement_IsSatisfiedBy(t *testing.T) {
+	client := NewClient("token").WithPermissions([]string{"support", "intern"})
+	scenarios := []struct {
+		name        string
+		requirement Requirement
+		expected    bool
+	}{
+		{name: "permission", requirement: Permission("support"), expected: true},
+		{name: "permission-missing", requirement: Permission("admin"), expected: false},
+		{name: "all-of", requirement: AllOf(Permission("support"), Permission("intern")), expected: true},
+		{name: "all-of-with-missing-permission", requirement: AllOf(Permission("support"), Permission("admin")), expected: false},
+		{name: "all-of-empty", requirement: AllOf(), expected: true},
+		{name: "any-of", requirement: AnyOf(Permission("admin"), Permission("support")), expected: true},
+		{name: "any-of-with-missing-permissions", requirement: AnyOf(Permission("admin"), Permission("owner")), expected: false},
+		{name: "any-of-empty", requirement: AnyOf(), expected: false},
+		{name: "not", requirement: Not(Permission("admin")), expected: true},
+		{name: "not-with-permission", requirement: Not(Permission("intern")), expected: false},
+		{name: "all-permissions", requirement: AllPermissions("support", "intern"), expected: true},
+		{name: "all-permissions-with-missing-permission", requirement: AllPermissions("support", "admin"), expected: false},
+		{name: "all-permissions-empty", requirement: AllPermissions(), expected: true},
+		{name: "any-permission", requirement: AnyPermission("admin", "support"), expected: true},
+		{name: "any-permission-with-missing-permissions", requirement: AnyPermission("admin", "owner"), expected: false},
+		{
+			name:        "nested",
+			requirement: AnyOf(Permission("admin"), AllOf(Permission("support"), Not(Permission("intern")))),
+			expected:    false,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if satisfied := scenario.requirement.IsSatisfiedBy(client); satisfied != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, satisfied)
+			}
+		})
+	}
+}
+
+func TestNot_IsSatisfiedByWithImpliedPermissions(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		client      *Client
+		requirement Requirement
+		expected    bool
+	}{
+		{name: "wildcard", client: NewClient("token").WithPermission(PermissionWildcard), requirement: Not(Permission("suspended")), expected: true},
+		{name: "wildcard-with-explicit-permission", client: NewClient("token").WithPermissions([]string{PermissionWildcard, "suspended"}), requirement: Not(Permission("suspended")), expected: false},
+		{name: "parent", client: NewClient("token").WithPermission("admin"), requirement: Not(Permission("admin:readonly")), expected: true},
+		{name: "namespace-wildcard", client: NewClient("token").WithPermission("repo:*"), requirement: Not(AnyOf(Permission("repo:archived"), Permission("intern"))), expected: true},
+		{name: "not-indexed", client: &Client{Token: "token", Permissions: []string{PermissionWildcard, "intern"}}, requirement: Not(Permission("intern")), expected: false},
+		{name: "double-negation", client: NewClient("token").WithPermission(PermissionWildcard), requirement: Not(Not(Permission("read"))), expected: true},
+		{name: "expression", client: NewClient("token").WithPermission(PermissionWildcard), requirement: MustParseRequirement("(admin || (billing && read)) && !suspended"), expected: true},
+		{name: "role", client: &Client{Token: "token", Roles: []string{"intern"}, rolePermissionSets: map[string]permissionSet{"intern": newPermissionSet([]string{"intern"})}}, requirement: Not(Permission("intern")), expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if satisfied := scenario.requirement.IsSatisfiedBy(scenario.client); satisfied != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, satisfied)
+			}
+		})
+	}
+}
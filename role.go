@@ -0,0 +1,128 @@
+package g8
+
+import (
+	"strings"
+)
+
+// Role is a named set of permissions that can be assigned to clients through Client.WithRole, rather than assigning
+// the same permissions to each client individually.
+//
+// A role may inherit other roles, in which case it also has every permission of the roles it inherits, recursively.
+// Roles must be registered on the AuthorizationService using AuthorizationService.WithRole.
+type Role struct {
+	// Name is the name of the role, which is what clients refer to
+	Name string
+
+	// Permissions is a slice of permissions that clients with the role have
+	Permissions []string
+
+	// InheritedRoles is a slice of names of roles whose permissions are inherited by the role
+	InheritedRoles []string
+}
+
+// NewRole creates a Role with a given name
+func NewRole(name string) *Role {
+	return &Role{
+		Name: name,
+	}
+}
+
+// WithPermissions adds a slice of permissions to a role
+func (role *Role) WithPermissions(permissions []string) *Role {
+	role.Permissions = append(role.Permissions, permissions...)
+	return role
+}
+
+// WithPermission adds a permission to a role
+func (role *Role) WithPermission(permission string) *Role {
+	role.Permissions = append(role.Permissions, permission)
+	return role
+}
+
+// WithInheritedRoles makes a role inherit the permissions of a slice of other roles
+func (role *Role) WithInheritedRoles(roleNames []string) *Role {
+	role.InheritedRoles = append(role.InheritedRoles, roleNames...)
+	return role
+}
+
+// WithInheritedRole makes a role inherit the permissions of another role
+func (role *Role) WithInheritedRole(roleName string) *Role {
+	role.InheritedRoles = append(role.InheritedRoles, roleName)
+	return role
+}
+
+// clone returns a copy of a role that doesn't share its Permissions and InheritedRoles with the original
+func (role *Role) clone() *Role {
+	return &Role{
+		Name:           role.Name,
+		Permissions:    append([]string(nil), role.Permissions...),
+		InheritedRoles: append([]string(nil), role.InheritedRoles...),
+	}
+}
+
+// findRoleCycle looks for a cycle in the inheritance graph of roles that goes through the role with the given name,
+// and returns the names of the roles that form said cycle (e.g. [admin editor admin]), or nil if there's none.
+func findRoleCycle(roles map[string]*Role, roleName string) []string {
+	var path []string
+	visited := make(map[string]bool)
+	var visit func(currentRoleName string) bool
+	visit = func(currentRoleName string) bool {
+		path = append(path, currentRoleName)
+		if len(path) > 1 && currentRoleName == roleName {
+			return true
+		}
+		if !visited[currentRoleName] {
+			visited[currentRoleName] = true
+			if role, exists := roles[currentRoleName]; exists {
+				for _, inheritedRoleName := range role.InheritedRoles {
+					if visit(inheritedRoleName) {
+						return true
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(roleName) {
+		return path
+	}
+	return nil
+}
+
+// resolveRolePermissions computes the effective permissions of every role, including the permissions of the roles
+// they inherit, recursively.
+//
+// Inherited roles that do not exist are ignored. The roles should not have any cycle, but if they do, the inheritance
+// that closes the cycle is ignored rather than resolved endlessly.
+func resolveRolePermissions(roles map[string]*Role) map[string][]string {
+	rolePermissions := make(map[string][]string, len(roles))
+	resolving := make(map[string]bool)
+	var resolve func(roleName string) []string
+	resolve = func(roleName string) []string {
+		if permissions, resolved := rolePermissions[roleName]; resolved {
+			return permissions
+		}
+		role, exists := roles[roleName]
+		if !exists || resolving[roleName] {
+			return nil
+		}
+		resolving[roleName] = true
+		defer delete(resolving, roleName)
+		permissions := append([]string(nil), role.Permissions...)
+		for _, inheritedRoleName := range role.InheritedRoles {
+			permissions = append(permissions, resolve(inheritedRoleName)...)
+		}
+		rolePermissions[roleName] = permissions
+		return permissions
+	}
+	for roleName := range roles {
+		resolve(roleName)
+	}
+	return rolePermissions
+}
+
+// formatRoleCycle formats a cycle returned by findRoleCycle for use in error messages
+func formatRoleCycle(cycle []string) string {
+	return strings.Join(cycle, " -> ")
+}
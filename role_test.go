@@ -0,0 +1,73 @@
+package g8
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestRole(t *testing.T) {
+	role := NewRole("editor").WithPermission("a").WithPermissions([]string{"b", "c"}).WithInheritedRole("viewer").WithInheritedRoles([]string{"commenter"})
+	if role.Name != "editor" {
+		t.Errorf("expected name to be %s, got %s", "editor", role.Name)
+	}
+	if !slices.Equal(role.Permissions, []string{"a", "b", "c"}) {
+		t.Errorf("expected permissions to be %v, got %v", []string{"a", "b", "c"}, role.Permissions)
+	}
+	if !slices.Equal(role.InheritedRoles, []string{"viewer", "commenter"}) {
+		t.Errorf("expected inherited roles to be %v, got %v", []string{"viewer", "commenter"}, role.InheritedRoles)
+	}
+}
+
+func TestFindRoleCycle(t *testing.T) {
+	roles := map[string]*Role{
+		"viewer": NewRole("viewer"),
+		"editor": NewRole("editor").WithInheritedRole("viewer"),
+		"admin":  NewRole("admin").WithInheritedRoles([]string{"editor", "undefined"}),
+	}
+	for roleName := range roles {
+		if cycle := findRoleCycle(roles, roleName); cycle != nil {
+			t.Errorf("expected no cycle for %s, got %v", roleName, cycle)
+		}
+	}
+	roles["viewer"].WithInheritedRole("admin")
+	if cycle := findRoleCycle(roles, "viewer"); !slices.Equal(cycle, []string{"viewer", "admin", "editor", "viewer"}) {
+		t.Errorf("expected cycle to be %v, got %v", []string{"viewer", "admin", "editor", "viewer"}, cycle)
+	}
+	roles["self"] = NewRole("self").WithInheritedRole("self")
+	if cycle := findRoleCycle(roles, "self"); !slices.Equal(cycle, []string{"self", "self"}) {
+		t.Errorf("expected cycle to be %v, got %v", []string{"self", "self"}, cycle)
+	}
+}
+
+func TestResolveRolePermissions(t *testing.T) {
+	roles := map[string]*Role{
+		"viewer": NewRole("viewer").WithPermission("read"),
+		"editor": NewRole("editor").WithPermission("write").WithInheritedRoles([]string{"viewer", "undefined"}),
+		"admin":  NewRole("admin").WithPermission("delete").WithInheritedRole("editor"),
+	}
+	rolePermissions := resolveRolePermissions(roles)
+	permissions := rolePermissions["admin"]
+	sort.Strings(permissions)
+	if !slices.Equal(permissions, []string{"delete", "read", "write"}) {
+		t.Errorf("expected admin permissions to be %v, got %v", []string{"delete", "read", "write"}, permissions)
+	}
+	if !slices.Equal(rolePermissions["viewer"], []string{"read"}) {
+		t.Errorf("expected viewer permissions to be %v, got %v", []string{"read"}, rolePermissions["viewer"])
+	}
+}
+
+func TestResolveRolePermissionsWithCycle(t *testing.T) {
+	roles := map[string]*Role{
+		"viewer": NewRole("viewer").WithPermission("read").WithInheritedRole("editor"),
+		"editor": NewRole("editor").WithPermission("write").WithInheritedRole("viewer"),
+	}
+	// The cycle must not be resolved endlessly, and each role must still have its own permissions
+	rolePermissions := resolveRolePermissions(roles)
+	if !slices.Contains(rolePermissions["viewer"], "read") {
+		t.Errorf("expected viewer permissions to contain %s, got %v", "read", rolePermissions["viewer"])
+	}
+	if !slices.Contains(rolePermissions["editor"], "write") {
+		t.Errorf("expected editor permissions to contain %s, got %v", "write", rolePermissions["editor"])
+	}
+}
@@ -0,0 +1,191 @@
+package g8
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlidingWindowRateLimiter is a sliding window counter rate limiter.
+//
+// Unlike RateLimiter, which resets its quota abruptly at the end of each window and thus allows up to twice the quota
+// to be used around window boundaries, SlidingWindowRateLimiter weighs the number of executions in the previous window
+// based on how much of it overlaps with a window ending now. This closely approximates the number of executions in
+// the last window duration, regardless of when that duration starts.
+type SlidingWindowRateLimiter struct {
+	maximumExecutions int
+	window            time.Duration
+
+	currentWindowStartTime  time.Time
+	currentWindowExecutions int
+	// currentWindow is the number of windows that have started since the creation of the rate limiter, which is used
+	// to refund units to the window they were consumed from
+	currentWindow int64
+	// previousWindowExecutions is the number of executions in the window that immediately precedes the current one
+	previousWindowExecutions int
+
+	mutex sync.Mutex
+}
+
+// NewSlidingWindowRateLimiter creates a SlidingWindowRateLimiter that allows at most maximumExecutions over any
+// window of the given duration.
+//
+// For instance, the following would allow 1000 executions per 10 minutes:
+//
+//	rateLimiter := g8.NewSlidingWindowRateLimiter(1000, 10*time.Minute)
+//
+// Panics if maximumExecutions or window is not positive, since every execution would be rejected, or the window could
+// never slide.
+func NewSlidingWindowRateLimiter(maximumExecutions int, window time.Duration) *SlidingWindowRateLimiter {
+	if maximumExecutions <= 0 || window <= 0 {
+		panic("g8: sliding window maximum executions and duration must be positive")
+	}
+	return &SlidingWindowRateLimiter{
+		maximumExecutions:      maximumExecutions,
+		window:                 window,
+		currentWindowStartTime: time.Now(),
+	}
+}
+
+// Try updates the number of executions if the rate limit quota hasn't been reached and returns whether the
+// attempt was successful or not.
+//
+// Returns false if the execution was not successful (rate limit quota has been reached)
+// Returns true if the execution was successful (rate limit quota has not been reached)
+func (r *SlidingWindowRateLimiter) Try() bool {
+	return r.TryN(1)
+}
+
+// TryN does the same thing as Try, but consumes n units of the rate limit quota instead of 1, which is useful if some
+// executions are more expensive than others.
+//
+// The attempt either consumes all n units or none of them.
+func (r *SlidingWindowRateLimiter) TryN(n int) bool {
+	return r.TakeN(n).Allowed
+}
+
+// Take does the same thing as Try, but returns a RateLimitResult instead of a bool, which includes the estimated
+// number of executions left in the sliding window and the time until executions in the sliding window have fully
+// expired.
+func (r *SlidingWindowRateLimiter) Take() RateLimitResult {
+	return r.TakeN(1)
+}
+
+// TakeN does the same thing as TryN, but returns a RateLimitResult instead of a bool.
+//
+// If n is greater than the maximum number of executions, the attempt can never be successful, in which case
+// RetryAfter is the duration until every execution has slid out of the sliding window, like ResetAfter.
+//
+// A negative n is treated as 0.
+func (r *SlidingWindowRateLimiter) TakeN(n int) RateLimitResult {
+	n = max(0, n)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	r.slide(now)
+	result := RateLimitResult{Limit: r.maximumExecutions}
+	// The attempt is allowed as long as the estimate is below the maximum before the last of the n executions
+	threshold := r.maximumExecutions - (n - 1)
+	if n > 0 && r.estimatedExecutions(now) >= float64(threshold) {
+		result.Remaining = max(0, int(float64(r.maximumExecutions)-r.estimatedExecutions(now)))
+		result.RetryAfter = r.durationUntilAvailable(now, threshold)
+		result.ResetAfter = r.durationUntilReset(now)
+		return result
+	}
+	r.currentWindowExecutions += n
+	result.Allowed = true
+	result.Window = r.currentWindow
+	result.Remaining = max(0, int(float64(r.maximumExecutions)-r.estimatedExecutions(now)))
+	result.ResetAfter = r.durationUntilReset(now)
+	return result
+}
+
+// RefundN gives back n units of the rate limit quota consumed by a successful call to TakeN.
+//
+// The units are given back to the window they were consumed from, so if the window has moved forward since the call
+// to TakeN, they are deducted from the executions of the previous window, which only count partially toward the
+// quota. If that window has entirely slid out of the sliding window, the refund is ignored.
+func (r *SlidingWindowRateLimiter) RefundN(n int, window int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.slide(time.Now())
+	switch window {
+	case r.currentWindow:
+		r.currentWindowExecutions = max(0, r.currentWindowExecutions-n)
+	case r.currentWindow - 1:
+		r.previousWindowExecutions = max(0, r.previousWindowExecutions-n)
+	}
+}
+
+// Wait blocks until the rate limit quota is available and consumes it, or until the context is done, whichever comes
+// first.
+//
+// Returns nil if the execution was successful, ctx.Err() if the context was done before the quota was available,
+// or ErrWaitExceedsDeadline if the quota will not be available before the context's deadline.
+func (r *SlidingWindowRateLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN does the same thing as Wait, but waits for n units of the rate limit quota instead of 1.
+//
+// Returns ErrCostExceedsLimit right away if n is greater than the rate limit quota.
+func (r *SlidingWindowRateLimiter) WaitN(ctx context.Context, n int) error {
+	_, err := waitForLimiter(ctx, r, n)
+	return err
+}
+
+// slide moves the current window forward if it has ended
+func (r *SlidingWindowRateLimiter) slide(now time.Time) {
+	elapsedWindows := now.Sub(r.currentWindowStartTime) / r.window
+	if elapsedWindows < 1 {
+		return
+	}
+	if elapsedWindows == 1 {
+		r.previousWindowExecutions = r.currentWindowExecutions
+	} else {
+		// More than one window has elapsed without any executions, so there's nothing left to carry over
+		r.previousWindowExecutions = 0
+	}
+	r.currentWindowExecutions = 0
+	r.currentWindowStartTime = r.currentWindowStartTime.Add(elapsedWindows * r.window)
+	r.currentWindow += int64(elapsedWindows)
+}
+
+// estimatedExecutions approximates the number of executions over the window ending now
+func (r *SlidingWindowRateLimiter) estimatedExecutions(now time.Time) float64 {
+	previousWindowWeight := 1 - float64(now.Sub(r.currentWindowStartTime))/float64(r.window)
+	return float64(r.previousWindowExecutions)*previousWindowWeight + float64(r.currentWindowExecutions)
+}
+
+// durationUntilAvailable returns the duration until the estimated number of executions drops below the threshold
+func (r *SlidingWindowRateLimiter) durationUntilAvailable(now time.Time, threshold int) time.Duration {
+	if threshold <= 0 {
+		// The estimate can never drop below the threshold, so the best we can do is to wait for an empty window
+		return r.durationUntilReset(now)
+	}
+	elapsed := now.Sub(r.currentWindowStartTime)
+	if r.currentWindowExecutions >= threshold {
+		// The executions of the current window alone exceed the quota, so we need to wait until the current window
+		// has ended and enough of it has slid out of the sliding window
+		overlap := max(0, float64(threshold)/float64(r.currentWindowExecutions))
+		return r.window - elapsed + time.Duration((1-overlap)*float64(r.window))
+	}
+	if r.previousWindowExecutions == 0 {
+		return 0
+	}
+	// The previous window's weight must drop enough for the estimate to fall below the threshold
+	overlap := float64(threshold-r.currentWindowExecutions) / float64(r.previousWindowExecutions)
+	return max(0, time.Duration((1-overlap)*float64(r.window))-elapsed)
+}
+
+// durationUntilReset returns the duration until every execution has slid out of the sliding window
+func (r *SlidingWindowRateLimiter) durationUntilReset(now time.Time) time.Duration {
+	currentWindowEndTime := r.currentWindowStartTime.Add(r.window)
+	if r.currentWindowExecutions > 0 {
+		return currentWindowEndTime.Add(r.window).Sub(now)
+	}
+	if r.previousWindowExecutions > 0 {
+		return currentWindowEndTime.Sub(now)
+	}
+	return 0
+}
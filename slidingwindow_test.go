@@ -0,0 +1,198 @@
+package g8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowRateLimiter_Try(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(5, time.Minute)
+	for i := 0; i < 20; i++ {
+		notRateLimited := rl.Try()
+		if i < 5 {
+			if !notRateLimited {
+				t.Fatal("expected to not be rate limited")
+			}
+		} else {
+			if notRateLimited {
+				t.Fatal("expected to be rate limited")
+			}
+		}
+	}
+}
+
+func TestSlidingWindowRateLimiter_TryAcrossWindowBoundary(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(4, 200*time.Millisecond)
+	// Pretend that the current window started 150ms ago and that 5 executions happened then
+	now := time.Now()
+	rl.currentWindowStartTime = now.Add(-150 * time.Millisecond)
+	rl.currentWindowExecutions = 5
+	if rl.Try() {
+		t.Fatal("expected to be rate limited")
+	}
+	// Move the window 100ms forward; half of the previous window still overlaps with the sliding window, so only
+	// 2.5 of the 5 previous executions should count toward the quota.
+	rl.currentWindowStartTime = now.Add(-300 * time.Millisecond)
+	if !rl.Try() || !rl.Try() {
+		t.Fatal("expected to not be rate limited")
+	}
+	if rl.Try() {
+		t.Error("expected to be rate limited, because a fixed window rate limiter would've allowed 4 executions")
+	}
+}
+
+func TestSlidingWindowRateLimiter_TryAfterSeveralWindows(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(2, 50*time.Millisecond)
+	if !rl.Try() || !rl.Try() {
+		t.Fatal("expected to not be rate limited")
+	}
+	if rl.Try() {
+		t.Fatal("expected to be rate limited")
+	}
+	time.Sleep(110 * time.Millisecond)
+	// More than one full window has passed, so the quota should be entirely available again
+	if !rl.Try() || !rl.Try() {
+		t.Error("expected to not be rate limited")
+	}
+	if rl.previousWindowExecutions != 0 {
+		t.Errorf("expected previousWindowExecutions to be %d, got %d", 0, rl.previousWindowExecutions)
+	}
+}
+
+func TestSlidingWindowRateLimiter_Take(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(2, time.Minute)
+	result := rl.Take()
+	if !result.Allowed || result.Limit != 2 || result.Remaining != 1 || result.RetryAfter != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// The execution will only have fully slid out of the sliding window once the next window has ended
+	if result.ResetAfter <= time.Minute || result.ResetAfter > 2*time.Minute {
+		t.Errorf("expected ResetAfter to be between 1m and 2m, got %s", result.ResetAfter)
+	}
+	rl.Take()
+	result = rl.Take()
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// Both executions will start sliding out of the sliding window once the current window ends, in ~1m
+	if result.RetryAfter <= 59*time.Second || result.RetryAfter > time.Minute {
+		t.Errorf("expected RetryAfter to be ~1m, got %s", result.RetryAfter)
+	}
+}
+
+func TestSlidingWindowRateLimiter_TakeWhenPreviousWindowExceededQuota(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(4, time.Minute)
+	rl.previousWindowExecutions = 5
+	result := rl.Take()
+	if result.Allowed {
+		t.Fatal("expected to be rate limited")
+	}
+	// A fifth of the previous window must slide out of the sliding window for an execution to be allowed
+	if result.RetryAfter <= 11*time.Second || result.RetryAfter > 12*time.Second {
+		t.Errorf("expected RetryAfter to be ~12s, got %s", result.RetryAfter)
+	}
+	if result.ResetAfter <= 59*time.Second || result.ResetAfter > time.Minute {
+		t.Errorf("expected ResetAfter to be ~1m, got %s", result.ResetAfter)
+	}
+}
+
+func TestSlidingWindowRateLimiter_TakeN(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(10, time.Minute)
+	if !rl.TryN(8) {
+		t.Error("expected to not be rate limited")
+	}
+	if rl.TryN(3) {
+		t.Error("expected to be rate limited, since only 2 executions are left")
+	}
+	if !rl.TryN(2) {
+		t.Error("expected to not be rate limited")
+	}
+}
+
+func TestSlidingWindowRateLimiter_TakeNWhenPreviousWindowExceededQuota(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(10, time.Minute)
+	rl.previousWindowExecutions = 10
+	result := rl.TakeN(3)
+	if result.Allowed {
+		t.Fatal("expected to be rate limited")
+	}
+	// A fifth of the previous window must slide out of the sliding window for 3 executions to be allowed
+	if result.RetryAfter <= 11*time.Second || result.RetryAfter > 12*time.Second {
+		t.Errorf("expected RetryAfter to be ~12s, got %s", result.RetryAfter)
+	}
+}
+
+func TestSlidingWindowRateLimiter_TakeNWhenNExceedsMaximumExecutions(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(2, time.Minute)
+	result := rl.TakeN(3)
+	if result.Allowed {
+		t.Fatal("expected to be rate limited")
+	}
+	// The window is empty, so there's nothing to wait for, even though the attempt can never be successful
+	if result.RetryAfter != 0 {
+		t.Errorf("expected RetryAfter to be 0, got %s", result.RetryAfter)
+	}
+	rl.Take()
+	result = rl.TakeN(3)
+	if result.RetryAfter <= time.Minute || result.RetryAfter > 2*time.Minute {
+		t.Errorf("expected RetryAfter to be between 1m and 2m, got %s", result.RetryAfter)
+	}
+	if result.RetryAfter != result.ResetAfter {
+		t.Errorf("expected RetryAfter to be equal to ResetAfter, got %s and %s", result.RetryAfter, result.ResetAfter)
+	}
+}
+
+func TestNewSlidingWindowRateLimiterWithInvalidParameters(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		maximumExecutions int
+		window            time.Duration
+	}{
+		{name: "zero-window", maximumExecutions: 10, window: 0},
+		{name: "negative-window", maximumExecutions: 10, window: -time.Second},
+		{name: "zero-maximum-executions", maximumExecutions: 0, window: time.Second},
+		{name: "negative-maximum-executions", maximumExecutions: -1, window: time.Second},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected NewSlidingWindowRateLimiter to panic")
+				}
+			}()
+			NewSlidingWindowRateLimiter(scenario.maximumExecutions, scenario.window)
+		})
+	}
+}
+
+func TestSlidingWindowRateLimiter_RefundN(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(3, time.Minute)
+	result := rl.TakeN(3)
+	rl.RefundN(2, result.Window)
+	if !rl.TryN(2) {
+		t.Error("expected the refunded units to be available")
+	}
+	rl.RefundN(10, result.Window)
+	if rl.currentWindowExecutions != 0 {
+		t.Errorf("expected refund to not go below zero, got %d executions", rl.currentWindowExecutions)
+	}
+}
+
+func TestSlidingWindowRateLimiter_RefundNAfterWindowMovedForward(t *testing.T) {
+	rl := NewSlidingWindowRateLimiter(10, time.Minute)
+	result := rl.TakeN(4)
+	// Move the current window forward by one window, which makes the executions above those of the previous window
+	rl.currentWindowStartTime = rl.currentWindowStartTime.Add(-time.Minute)
+	rl.TakeN(1)
+	rl.RefundN(3, result.Window)
+	if rl.previousWindowExecutions != 1 || rl.currentWindowExecutions != 1 {
+		t.Errorf("expected the refund to be deducted from the previous window, got %d executions in the previous window and %d in the current one", rl.previousWindowExecutions, rl.currentWindowExecutions)
+	}
+	// Once the window the units were consumed from has slid out entirely, the refund must be ignored
+	rl.currentWindowStartTime = rl.currentWindowStartTime.Add(-time.Minute)
+	rl.TakeN(1)
+	rl.RefundN(1, result.Window)
+	if rl.previousWindowExecutions != 1 || rl.currentWindowExecutions != 1 {
+		t.Errorf("expected the refund to be ignored, got %d executions in the previous window and %d in the current one", rl.previousWindowExecutions, rl.currentWindowExecutions)
+	}
+}
@@ -0,0 +1,87 @@
+package g8
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TwiN/gocache/v2"
+)
+
+// Store is the interface that must be implemented by a storage for counters, which allows a StoreRateLimiter to
+// share its quota with every other StoreRateLimiter using the same Store and key, even across several instances of
+// an application.
+//
+// g8 comes with MemoryStore, an in-memory implementation, but since it is local to a single process, you'll want to
+// implement a Store backed by something shared (e.g. Redis' INCRBY, PEXPIRE and GET) if you have more than one instance.
+type Store interface {
+	// Increment increments the counter associated with a key by delta and returns the updated value of the counter
+	// as well as the time left before the counter expires.
+	//
+	// If the counter does not exist or has expired, it must be created with a value of delta and set to expire after
+	// the expiration passed as parameter. The expiration of an existing counter must not be modified.
+	Increment(key string, delta int, expiration time.Duration) (count int, ttl time.Duration, err error)
+
+	// Get returns the value of the counter associated with a key, or 0 if the counter does not exist or has expired.
+	//
+	// Unlike Increment, it must not create the counter.
+	Get(key string) (count int, err error)
+}
+
+// MemoryStore is an in-memory implementation of Store.
+//
+// Note that because counters are kept in the memory of the current process, a MemoryStore cannot be used to share
+// quotas across several instances of an application.
+type MemoryStore struct {
+	cache *gocache.Cache
+	mutex sync.Mutex
+}
+
+// memoryStoreCounter is a counter stored in a MemoryStore
+type memoryStoreCounter struct {
+	value     int
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a MemoryStore that can hold up to maxSize counters.
+//
+// If maxSize is reached, the least recently used counter is evicted.
+func NewMemoryStore(maxSize int) *MemoryStore {
+	return &MemoryStore{
+		cache: gocache.NewCache().WithEvictionPolicy(gocache.LeastRecentlyUsed).WithMaxSize(maxSize),
+	}
+}
+
+// Increment increments the counter associated with a key by delta and returns the updated value of the counter as
+// well as the time left before the counter expires.
+//
+// If the counter does not exist or has expired, it is created with a value of delta and set to expire after the
+// expiration passed as parameter.
+func (store *MemoryStore) Increment(key string, delta int, expiration time.Duration) (int, time.Duration, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	now := time.Now()
+	if value, exists := store.cache.Get(key); exists {
+		if counter, ok := value.(*memoryStoreCounter); ok && now.Before(counter.expiresAt) {
+			counter.value += delta
+			return counter.value, counter.expiresAt.Sub(now), nil
+		}
+	}
+	counter := &memoryStoreCounter{value: delta, expiresAt: now.Add(expiration)}
+	store.cache.SetWithTTL(key, counter, expiration)
+	return counter.value, expiration, nil
+}
+
+// Get returns the value of the counter associated with a key, or 0 if the counter does not exist or has expired
+func (store *MemoryStore) Get(key string) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if value, exists := store.cache.Get(key); exists {
+		if counter, ok := value.(*memoryStoreCounter); ok && time.Now().Before(counter.expiresAt) {
+			return counter.value, nil
+		}
+	}
+	return 0, nil
+}
+
+// Make sure that MemoryStore is compatible with the interface
+var _ Store = (*MemoryStore)(nil)
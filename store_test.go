@@ -0,0 +1,46 @@
+package g8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Increment(t *testing.T) {
+	store := NewMemoryStore(10)
+	if count, ttl, err := store.Increment("key", 1, time.Minute); err != nil || count != 1 || ttl != time.Minute {
+		t.Errorf("expected (1, 1m, nil), got (%d, %s, %v)", count, ttl, err)
+	}
+	if count, ttl, err := store.Increment("key", 5, time.Hour); err != nil || count != 6 || ttl > time.Minute {
+		t.Errorf("expected count to be 6 and ttl to be at most 1m, got (%d, %s, %v)", count, ttl, err)
+	}
+	if count, _, _ := store.Increment("other-key", 1, time.Minute); count != 1 {
+		t.Errorf("expected count to be 1, got %d", count)
+	}
+}
+
+func TestMemoryStore_IncrementAfterExpiration(t *testing.T) {
+	store := NewMemoryStore(10)
+	store.Increment("key", 3, 20*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if count, ttl, _ := store.Increment("key", 1, time.Minute); count != 1 || ttl != time.Minute {
+		t.Errorf("expected counter to have been reset, got (%d, %s)", count, ttl)
+	}
+}
+
+func TestMemoryStore_Get(t *testing.T) {
+	store := NewMemoryStore(10)
+	if count, err := store.Get("key"); err != nil || count != 0 {
+		t.Errorf("expected (0, nil), got (%d, %v)", count, err)
+	}
+	if store.cache.Count() != 0 {
+		t.Error("expected Get to not create the counter")
+	}
+	store.Increment("key", 3, 20*time.Millisecond)
+	if count, err := store.Get("key"); err != nil || count != 3 {
+		t.Errorf("expected (3, nil), got (%d, %v)", count, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if count, _ := store.Get("key"); count != 0 {
+		t.Errorf("expected expired counter to be 0, got %d", count)
+	}
+}
@@ -0,0 +1,140 @@
+package g8
+
+import (
+	"context"
+	"time"
+)
+
+// StoreRateLimiter is a fixed window rate limiter that keeps track of executions in a Store.
+//
+// Every StoreRateLimiter that uses the same Store and key shares the same quota, so if the Store is shared by several
+// instances of an application (e.g. through Redis), the quota will be enforced across all of them rather than per
+// instance.
+//
+// If the Store returns an error, the execution is allowed, as it's generally preferable to let requests through than
+// to reject every request whenever the Store is unavailable.
+type StoreRateLimiter struct {
+	store             Store
+	key               string
+	maximumExecutions int
+	window            time.Duration
+}
+
+// NewStoreRateLimiter creates a StoreRateLimiter that allows at most maximumExecutions per window, keeping track of
+// executions in the given store under the given key.
+//
+// For instance, the following would allow 100 executions per second across every instance using the same store:
+//
+//	rateLimiter := g8.NewStoreRateLimiter(yourRedisStore, "my-api", 100, time.Second)
+//
+// Panics if maximumExecutions or window is not positive, since every execution would either be rejected or start a
+// new window, in which case no limit would be enforced at all.
+func NewStoreRateLimiter(store Store, key string, maximumExecutions int, window time.Duration) *StoreRateLimiter {
+	if maximumExecutions <= 0 || window <= 0 {
+		panic("g8: store rate limiter maximum executions and window must be positive")
+	}
+	return &StoreRateLimiter{
+		store:             store,
+		key:               key,
+		maximumExecutions: maximumExecutions,
+		window:            window,
+	}
+}
+
+// Try updates the number of executions if the rate limit quota hasn't been reached and returns whether the
+// attempt was successful or not.
+//
+// Returns false if the execution was not successful (rate limit quota has been reached)
+// Returns true if the execution was successful (rate limit quota has not been reached)
+func (r *StoreRateLimiter) Try() bool {
+	return r.TryN(1)
+}
+
+// TryN does the same thing as Try, but consumes n units of the rate limit quota instead of 1, which is useful if some
+// executions are more expensive than others.
+//
+// The attempt either consumes all n units or none of them.
+func (r *StoreRateLimiter) TryN(n int) bool {
+	return r.TakeN(n).Allowed
+}
+
+// Take does the same thing as Try, but returns a RateLimitResult instead of a bool, which includes the number of
+// executions left in the current window and the time until the next window starts.
+func (r *StoreRateLimiter) Take() RateLimitResult {
+	return r.TakeN(1)
+}
+
+// TakeN does the same thing as TryN, but returns a RateLimitResult instead of a bool.
+//
+// A negative n is treated as 0.
+func (r *StoreRateLimiter) TakeN(n int) RateLimitResult {
+	n = max(0, n)
+	// The time is taken before calling the store so that the expiration computed below is never later than the actual
+	// expiration of the counter
+	now := time.Now()
+	count, ttl, err := r.store.Increment(r.key, n, r.window)
+	if err != nil {
+		// Fail open, see StoreRateLimiter
+		return RateLimitResult{Allowed: true, Limit: r.maximumExecutions, Remaining: r.maximumExecutions}
+	}
+	result := RateLimitResult{
+		Limit:      r.maximumExecutions,
+		Remaining:  max(0, r.maximumExecutions-count),
+		ResetAfter: ttl,
+	}
+	if count > r.maximumExecutions {
+		if count-n < r.maximumExecutions {
+			// Give back the units that were left before this attempt, otherwise an expensive attempt that was rejected
+			// would prevent cheaper ones from going through until the end of the window
+			_, _, _ = r.store.Increment(r.key, -n, r.window)
+			result.Remaining = r.maximumExecutions - (count - n)
+		}
+		result.RetryAfter = ttl
+		return result
+	}
+	result.Allowed = true
+	// The counter is identified by the time at which it expires, since a counter created after it expired expires
+	// at least one window later
+	result.Window = now.Add(ttl).UnixNano()
+	return result
+}
+
+// RefundN gives back n units of the rate limit quota consumed by a successful call to TakeN.
+//
+// The refund is ignored if the counter the units were consumed from has expired, or if the Store returns an error.
+func (r *StoreRateLimiter) RefundN(n int, window int64) {
+	expiresAt := time.Unix(0, window)
+	now := time.Now()
+	if !now.Before(expiresAt) {
+		return
+	}
+	count, ttl, err := r.store.Increment(r.key, -n, r.window)
+	if err != nil {
+		return
+	}
+	if now.Add(ttl).Sub(expiresAt) >= r.window/2 {
+		// The counter expired while the refund was on its way to the store, so the refund was applied to a counter
+		// created by other executions since, or created a new counter below zero. Either way, it must be undone, as it
+		// would otherwise allow more executions than the limit in the new window.
+		_, _, _ = r.store.Increment(r.key, n, r.window)
+	} else if count < 0 {
+		_, _, _ = r.store.Increment(r.key, -count, r.window)
+	}
+}
+
+// Wait blocks until the rate limit quota is available and consumes it, or until the context is done, whichever comes
+// first.
+//
+// Returns nil if the execution was successful, ctx.Err() if the context was done before the quota was available,
+// or ErrWaitExceedsDeadline if the quota will not be available before the context's deadline.
+func (r *StoreRateLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN does the same thing as Wait, but waits for n units of the rate limit quota instead of 1.
+//
+// Returns ErrCostExceedsLimit right away if n is greater than the rate limit quota.
+func (r *StoreRateLimiter) WaitN(ctx context.Context, n int) error {
+	_, err := waitForLimiter(ctx, r, n)
+	return err
+}
@@ -0,0 +1,149 @@
+package g8
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreRateLimiter_Take(t *testing.T) {
+	store := NewMemoryStore(10)
+	rl := NewStoreRateLimiter(store, "key", 2, time.Minute)
+	if result := rl.Take(); !result.Allowed || result.Limit != 2 || result.Remaining != 1 || result.ResetAfter != time.Minute {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := rl.Take(); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := rl.Take(); result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// Another rate limiter using the same store and key shares the same quota
+	if NewStoreRateLimiter(store, "key", 2, time.Minute).Try() {
+		t.Error("expected to be rate limited, since the quota is shared")
+	}
+	if !NewStoreRateLimiter(store, "other-key", 2, time.Minute).Try() {
+		t.Error("expected to not be rate limited, since the key is different")
+	}
+}
+
+func TestStoreRateLimiter_TakeWhenStoreReturnsError(t *testing.T) {
+	store := NewFakeStore()
+	store.WithError(errors.New("store unavailable"))
+	rl := NewStoreRateLimiter(store, "key", 1, time.Minute)
+	for i := 0; i < 3; i++ {
+		if !rl.Try() {
+			t.Error("expected to not be rate limited, since StoreRateLimiter should fail open")
+		}
+	}
+	if store.Calls() != 3 {
+		t.Errorf("expected store to have been called %d times, got %d", 3, store.Calls())
+	}
+}
+
+func TestGate_ProtectWithClientRateLimiterUsingStore(t *testing.T) {
+	store := NewFakeStore()
+	newLimiterFunc := func(token string) Limiter {
+		return NewStoreRateLimiter(store, "client:"+token, 1, time.Second)
+	}
+	// Two gates sharing the same store behave like two instances of the same application
+	authorizationService := NewAuthorizationService().WithToken("token")
+	firstGate := New().WithAuthorizationService(authorizationService).WithClientRateLimiter(newLimiterFunc)
+	secondGate := New().WithAuthorizationService(authorizationService).WithClientRateLimiter(newLimiterFunc)
+	if !firstGate.clientRateLimiterPool.get("token").TakeN(1).Allowed {
+		t.Error("expected first request to not be rate limited")
+	}
+	if secondGate.clientRateLimiterPool.get("token").TakeN(1).Allowed {
+		t.Error("expected second request to be rate limited, since the quota is shared through the store")
+	}
+	if store.Count("client:token") != 2 {
+		t.Errorf("expected counter to be %d, got %d", 2, store.Count("client:token"))
+	}
+}
+
+func TestStoreRateLimiter_TakeN(t *testing.T) {
+	store := NewFakeStore()
+	rl := NewStoreRateLimiter(store, "key", 10, time.Minute)
+	if result := rl.TakeN(7); !result.Allowed || result.Remaining != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := rl.TakeN(5); result.Allowed || result.Remaining != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// The units of the rejected attempt must have been given back
+	if store.Count("key") != 7 {
+		t.Errorf("expected counter to be %d, got %d", 7, store.Count("key"))
+	}
+	if !rl.TryN(3) {
+		t.Error("expected to not be rate limited")
+	}
+}
+
+func TestStoreRateLimiter_RefundN(t *testing.T) {
+	store := NewFakeStore()
+	rl := NewStoreRateLimiter(store, "key", 3, time.Minute)
+	result := rl.TakeN(3)
+	rl.RefundN(2, result.Window)
+	if store.Count("key") != 1 {
+		t.Errorf("expected counter to be %d, got %d", 1, store.Count("key"))
+	}
+	// The refund must never bring the counter below zero
+	rl.RefundN(5, result.Window)
+	if store.Count("key") != 0 {
+		t.Errorf("expected counter to be %d, got %d", 0, store.Count("key"))
+	}
+}
+
+func TestStoreRateLimiter_RefundNAfterCounterExpired(t *testing.T) {
+	store := NewMemoryStore(10)
+	rl := NewStoreRateLimiter(store, "key", 2, 50*time.Millisecond)
+	result := rl.TakeN(1)
+	time.Sleep(60 * time.Millisecond)
+	// Other executions create a new counter once the previous one has expired
+	if !rl.TryN(2) {
+		t.Fatal("expected the quota of the new window to be available")
+	}
+	// The unit was consumed from the expired counter, so refunding it must not allow a third execution in the new window
+	rl.RefundN(1, result.Window)
+	if rl.Try() {
+		t.Error("expected the refund of a unit consumed from an expired counter to be ignored")
+	}
+}
+
+func TestStoreRateLimiter_RefundNWhenCounterExpiresDuringRefund(t *testing.T) {
+	store := NewMemoryStore(10)
+	rl := NewStoreRateLimiter(store, "key", 2, time.Minute)
+	result := rl.TakeN(1)
+	// Simulate a counter that expired after the refund was issued but before it reached the store, and that was
+	// recreated by other executions since, by making the units look like they were consumed from a counter that
+	// expires well before the current one
+	store.cache.Delete("key")
+	rl.TryN(2)
+	rl.RefundN(1, result.Window-int64(40*time.Second))
+	if rl.Try() {
+		t.Error("expected the refund applied to the new counter to have been undone")
+	}
+}
+
+func TestNewStoreRateLimiterWithInvalidParameters(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		maximumExecutions int
+		window            time.Duration
+	}{
+		{name: "zero-window", maximumExecutions: 1, window: 0},
+		{name: "negative-window", maximumExecutions: 1, window: -time.Second},
+		{name: "zero-maximum-executions", maximumExecutions: 0, window: time.Second},
+		{name: "negative-maximum-executions", maximumExecutions: -1, window: time.Second},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected NewStoreRateLimiter to panic")
+				}
+			}()
+			NewStoreRateLimiter(NewMemoryStore(10), "key", scenario.maximumExecutions, scenario.window)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package g8
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucketRateLimiter is a token bucket rate limiter.
+//
+// The bucket starts full with burst tokens, each execution consumes one token, and tokens are added back to the bucket
+// at a constant rate until the bucket is full again. Unlike RateLimiter, this allows short bursts of up to burst
+// executions while enforcing an average rate over time, and there is no window boundary at which the quota is reset
+// all at once.
+type TokenBucketRateLimiter struct {
+	refillRatePerSecond float64
+	burst               int
+	tokens              float64
+	lastRefillTime      time.Time
+	mutex               sync.Mutex
+}
+
+// NewTokenBucketRateLimiter creates a TokenBucketRateLimiter that refills refillRatePerSecond tokens per second and
+// can hold at most burst tokens.
+//
+// For instance, the following would allow an average of 10 executions per second with bursts of up to 50 executions:
+//
+//	rateLimiter := g8.NewTokenBucketRateLimiter(10, 50)
+//
+// Panics if refillRatePerSecond or burst is not positive, since the bucket would never hold a token again.
+func NewTokenBucketRateLimiter(refillRatePerSecond float64, burst int) *TokenBucketRateLimiter {
+	if !(refillRatePerSecond > 0) || burst <= 0 {
+		panic("g8: token bucket refill rate and burst must be positive")
+	}
+	return &TokenBucketRateLimiter{
+		refillRatePerSecond: refillRatePerSecond,
+		burst:               burst,
+		tokens:              float64(burst),
+		lastRefillTime:      time.Now(),
+	}
+}
+
+// Try consumes a token if there's one available in the bucket and returns whether the attempt was successful or not.
+//
+// Returns false if the execution was not successful (the bucket is empty)
+// Returns true if the execution was successful (a token was consumed)
+func (r *TokenBucketRateLimiter) Try() bool {
+	return r.TryN(1)
+}
+
+// TryN does the same thing as Try, but consumes n units of the rate limit quota instead of 1, which is useful if some
+// executions are more expensive than others.
+//
+// The attempt either consumes all n units or none of them.
+func (r *TokenBucketRateLimiter) TryN(n int) bool {
+	return r.TakeN(n).Allowed
+}
+
+// Take does the same thing as Try, but returns a RateLimitResult instead of a bool, which includes the number of
+// tokens left in the bucket and the time until the bucket is full again.
+func (r *TokenBucketRateLimiter) Take() RateLimitResult {
+	return r.TakeN(1)
+}
+
+// TakeN does the same thing as TryN, but returns a RateLimitResult instead of a bool.
+//
+// A negative n is treated as 0.
+func (r *TokenBucketRateLimiter) TakeN(n int) RateLimitResult {
+	n = max(0, n)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.refill(time.Now())
+	result := RateLimitResult{Limit: r.burst}
+	if r.tokens < float64(n) {
+		result.Remaining = int(r.tokens)
+		result.RetryAfter = r.durationUntilTokens(float64(n))
+		result.ResetAfter = r.durationUntilTokens(float64(r.burst))
+		return result
+	}
+	r.tokens -= float64(n)
+	result.Allowed = true
+	result.Remaining = int(r.tokens)
+	result.ResetAfter = r.durationUntilTokens(float64(r.burst))
+	return result
+}
+
+// RefundN puts back n tokens consumed by a successful call to TakeN into the bucket, without exceeding the burst.
+//
+// Since a token bucket has no windows, the window passed as parameter is ignored.
+func (r *TokenBucketRateLimiter) RefundN(n int, _ int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tokens = min(float64(r.burst), r.tokens+float64(n))
+}
+
+// Wait blocks until the rate limit quota is available and consumes it, or until the context is done, whichever comes
+// first.
+//
+// Returns nil if the execution was successful, ctx.Err() if the context was done before the quota was available,
+// or ErrWaitExceedsDeadline if the quota will not be available before the context's deadline.
+func (r *TokenBucketRateLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN does the same thing as Wait, but waits for n units of the rate limit quota instead of 1.
+//
+// Returns ErrCostExceedsLimit right away if n is greater than the rate limit quota.
+func (r *TokenBucketRateLimiter) WaitN(ctx context.Context, n int) error {
+	_, err := waitForLimiter(ctx, r, n)
+	return err
+}
+
+// durationUntilTokens returns the duration until the bucket holds the given number of tokens
+func (r *TokenBucketRateLimiter) durationUntilTokens(tokens float64) time.Duration {
+	if r.tokens >= tokens {
+		return 0
+	}
+	return time.Duration((tokens - r.tokens) / r.refillRatePerSecond * float64(time.Second))
+}
+
+// refill adds the tokens accumulated since the last refill to the bucket, without exceeding the burst
+func (r *TokenBucketRateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(r.lastRefillTime); elapsed > 0 {
+		r.tokens += elapsed.Seconds() * r.refillRatePerSecond
+		if r.tokens > float64(r.burst) {
+			r.tokens = float64(r.burst)
+		}
+	}
+	r.lastRefillTime = now
+}
@@ -0,0 +1,155 @@
+package g8
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewTokenBucketRateLimiter(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(1, 3)
+	if rl.burst != 3 {
+		t.Errorf("expected burst to be %d, got %d", 3, rl.burst)
+	}
+	if rl.tokens != 3 {
+		t.Errorf("expected tokens to be %d, got %f", 3, rl.tokens)
+	}
+}
+
+func TestTokenBucketRateLimiter_Try(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(1, 5)
+	for i := 0; i < 20; i++ {
+		notRateLimited := rl.Try()
+		if i < 5 {
+			if !notRateLimited {
+				t.Fatal("expected to not be rate limited")
+			}
+		} else {
+			if notRateLimited {
+				t.Fatal("expected to be rate limited")
+			}
+		}
+	}
+}
+
+func TestTokenBucketRateLimiter_TryAfterRefill(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(20, 2)
+	if !rl.Try() || !rl.Try() {
+		t.Fatal("expected to not be rate limited")
+	}
+	if rl.Try() {
+		t.Fatal("expected to be rate limited")
+	}
+	// At 20 tokens per second, a token is added back every 50ms
+	time.Sleep(60 * time.Millisecond)
+	if !rl.Try() {
+		t.Error("expected to not be rate limited, since a token should've been added back to the bucket")
+	}
+	if rl.Try() {
+		t.Error("expected to be rate limited, since only one token should've been added back to the bucket")
+	}
+}
+
+func TestTokenBucketRateLimiter_RefillDoesNotExceedBurst(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(1000, 2)
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if !rl.Try() {
+			t.Fatal("expected to not be rate limited")
+		}
+	}
+	if rl.Try() {
+		t.Error("expected to be rate limited, because the bucket should not hold more than burst tokens")
+	}
+}
+
+func TestTokenBucketRateLimiter_Take(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(10, 2)
+	result := rl.Take()
+	if !result.Allowed || result.Limit != 2 || result.Remaining != 1 || result.RetryAfter != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// One token is missing from the bucket, and at 10 tokens per second, it takes ~100ms to refill a token
+	if result.ResetAfter <= 0 || result.ResetAfter > 100*time.Millisecond {
+		t.Errorf("expected ResetAfter to be at most 100ms, got %s", result.ResetAfter)
+	}
+	rl.Take()
+	result = rl.Take()
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 100*time.Millisecond {
+		t.Errorf("expected RetryAfter to be at most 100ms, got %s", result.RetryAfter)
+	}
+	if result.ResetAfter <= 100*time.Millisecond || result.ResetAfter > 200*time.Millisecond {
+		t.Errorf("expected ResetAfter to be between 100ms and 200ms, got %s", result.ResetAfter)
+	}
+}
+
+func TestTokenBucketRateLimiter_Wait(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(20, 1)
+	rl.Try()
+	start := time.Now()
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// At 20 tokens per second, a token is added back every 50ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("expected Wait to block for ~50ms, but it blocked for %s", elapsed)
+	}
+}
+
+func TestTokenBucketRateLimiter_TakeN(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(1, 10)
+	if result := rl.TakeN(8); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	result := rl.TakeN(5)
+	if result.Allowed || result.Remaining != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// 3 more tokens are needed, and the bucket is refilled at a rate of 1 token per second
+	if result.RetryAfter <= 2900*time.Millisecond || result.RetryAfter > 3*time.Second {
+		t.Errorf("expected RetryAfter to be ~3s, got %s", result.RetryAfter)
+	}
+	if !rl.TryN(2) {
+		t.Error("expected the rejected attempt to not have consumed any token")
+	}
+}
+
+func TestNewTokenBucketRateLimiterWithInvalidArguments(t *testing.T) {
+	scenarios := []struct {
+		name                string
+		refillRatePerSecond float64
+		burst               int
+	}{
+		{name: "zero-refill-rate", refillRatePerSecond: 0, burst: 10},
+		{name: "negative-refill-rate", refillRatePerSecond: -1, burst: 10},
+		{name: "nan-refill-rate", refillRatePerSecond: math.NaN(), burst: 10},
+		{name: "zero-burst", refillRatePerSecond: 1, burst: 0},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected NewTokenBucketRateLimiter to panic")
+				}
+			}()
+			NewTokenBucketRateLimiter(scenario.refillRatePerSecond, scenario.burst)
+		})
+	}
+}
+
+func TestTokenBucketRateLimiter_RefundN(t *testing.T) {
+	rl := NewTokenBucketRateLimiter(0.001, 3)
+	rl.TryN(3)
+	rl.RefundN(2, 0)
+	if !rl.TryN(2) {
+		t.Error("expected the refunded tokens to be available")
+	}
+	rl.RefundN(10, 0)
+	if rl.tokens > 3 {
+		t.Errorf("expected refund to not exceed the burst, got %f tokens", rl.tokens)
+	}
+}